@@ -0,0 +1,375 @@
+package tmx
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+//WangGrid contains the desired wang colors of all corners and edges of a
+//grid of tiles. Neighbouring tiles share their corners and edges.
+type WangGrid struct {
+	Width  int
+	Height int
+	//colors is a lattice of (2*Width+1)*(2*Height+1) points, the corners
+	//of tiles are at even coordinates and their edges in between
+	colors []uint8
+}
+
+//NewWangGrid creates a grid of the given size in tiles without colors
+func NewWangGrid(width, height int) *WangGrid {
+	return &WangGrid{
+		Width:  width,
+		Height: height,
+		colors: make([]uint8, (2*width+1)*(2*height+1)),
+	}
+}
+
+//wangOffsets are the lattice offsets of every WangPosition to the center of a tile
+var wangOffsets = [8][2]int{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+
+//set sets the color of a point of the lattice, points outside are ignored
+func (g *WangGrid) set(x, y int, color uint8) {
+	if x < 0 || y < 0 || x > 2*g.Width || y > 2*g.Height {
+		return
+	}
+
+	g.colors[y*(2*g.Width+1)+x] = color
+}
+
+//SetTile paints all corners and edges of the tile at x, y,
+//tiles outside of the grid are ignored
+func (g *WangGrid) SetTile(x, y int, color uint8) {
+	if x < 0 || y < 0 || x >= g.Width || y >= g.Height {
+		return
+	}
+
+	for _, offset := range wangOffsets {
+		g.set(2*x+1+offset[0], 2*y+1+offset[1], color)
+	}
+}
+
+//SetCorner paints the corner x, y which is shared by the tiles x-1 to x and
+//y-1 to y, corners range from 0 to Width and 0 to Height
+func (g *WangGrid) SetCorner(x, y int, color uint8) {
+	g.set(2*x, 2*y, color)
+}
+
+//SetEdge paints the edge at position of the tile x, y, corner
+//positions are ignored
+func (g *WangGrid) SetEdge(x, y int, position WangPosition, color uint8) {
+	if x < 0 || y < 0 || x >= g.Width || y >= g.Height || position%2 != 0 {
+		return
+	}
+
+	offset := wangOffsets[position]
+	g.set(2*x+1+offset[0], 2*y+1+offset[1], color)
+}
+
+//WangID returns the colors of the tile at x, y
+func (g WangGrid) WangID(x, y int) WangID {
+	var id WangID
+	if x < 0 || y < 0 || x >= g.Width || y >= g.Height {
+		return id
+	}
+
+	for i, offset := range wangOffsets {
+		id[i] = g.colors[(2*y+1+offset[1])*(2*g.Width+1)+2*x+1+offset[0]]
+	}
+
+	return id
+}
+
+//AutoTileOptions configure AutoTile
+type AutoTileOptions struct {
+	//Rand chooses between tiles that match equally well,
+	//the default source of math/rand is used if nil
+	Rand *rand.Rand
+}
+
+//wangTransform is a combination of tile flips and the
+//permutation of WangID positions it causes
+type wangTransform struct {
+	flips       GID
+	permutation [8]int
+}
+
+//apply returns the colors of id after transforming its tile
+func (t wangTransform) apply(id WangID) WangID {
+	var result WangID
+	for i, source := range t.permutation {
+		result[i] = id[source]
+	}
+
+	return result
+}
+
+//then returns the transform that applies t and next afterwards
+func (t wangTransform) then(next wangTransform) [8]int {
+	var permutation [8]int
+	for i := range permutation {
+		permutation[i] = t.permutation[next.permutation[i]]
+	}
+
+	return permutation
+}
+
+//flipTransforms contains the transforms of all combinations
+//of flips, Tiled applies the diagonal flip first
+var flipTransforms = func() []wangTransform {
+	transpose := wangTransform{flips: GIDDiagonalFlip}
+	horizontal := wangTransform{flips: GIDHorizontalFlip}
+	vertical := wangTransform{flips: GIDVerticalFlip}
+	identity := wangTransform{}
+	for i := 0; i < 8; i++ {
+		transpose.permutation[i] = (14 - i) % 8
+		horizontal.permutation[i] = (8 - i) % 8
+		vertical.permutation[i] = (12 - i) % 8
+		identity.permutation[i] = i
+	}
+
+	var transforms []wangTransform
+	for _, d := range []bool{false, true} {
+		for _, h := range []bool{false, true} {
+			for _, v := range []bool{false, true} {
+				t := identity
+				if d {
+					t = wangTransform{flips: t.flips | transpose.flips, permutation: t.then(transpose)}
+				}
+
+				if h {
+					t = wangTransform{flips: t.flips | horizontal.flips, permutation: t.then(horizontal)}
+				}
+
+				if v {
+					t = wangTransform{flips: t.flips | vertical.flips, permutation: t.then(vertical)}
+				}
+
+				transforms = append(transforms, t)
+			}
+		}
+	}
+
+	return transforms
+}()
+
+//allowedTransforms returns all transforms that can be created from
+//the allowed transformations, the first transform is the identity
+func allowedTransforms(allowed *Transformations) []wangTransform {
+	identity := flipTransforms[0]
+	if allowed == nil {
+		return []wangTransform{identity}
+	}
+
+	var generators []GID
+	if allowed.Rotate {
+		//a clockwise rotation by 90 degrees
+		generators = append(generators, GIDDiagonalFlip|GIDHorizontalFlip)
+	}
+
+	if allowed.HFlip {
+		generators = append(generators, GIDHorizontalFlip)
+	}
+
+	if allowed.VFlip {
+		generators = append(generators, GIDVerticalFlip)
+	}
+
+	byPermutation := map[[8]int]wangTransform{}
+	for _, t := range flipTransforms {
+		byPermutation[t.permutation] = t
+	}
+
+	byFlips := map[GID]wangTransform{}
+	for _, t := range flipTransforms {
+		byFlips[t.flips] = t
+	}
+
+	result := []wangTransform{identity}
+	found := map[GID]bool{identity.flips: true}
+	for i := 0; i < len(result); i++ {
+		for _, generator := range generators {
+			next := byPermutation[result[i].then(byFlips[generator])]
+			if !found[next.flips] {
+				found[next.flips] = true
+				result = append(result, next)
+			}
+		}
+	}
+
+	return result
+}
+
+//wangCandidate is a transformed tile of a wang set
+type wangCandidate struct {
+	tile   DataTile
+	id     WangID
+	weight float64
+	//transformed is true if the tile is flipped or rotated
+	transformed bool
+}
+
+//wangPositions returns the positions that are used by sets of the given type
+func wangPositions(setType string) []WangPosition {
+	switch setType {
+	case "corner":
+		return []WangPosition{WangTopRight, WangBottomRight, WangBottomLeft, WangTopLeft}
+	case "edge":
+		return []WangPosition{WangTop, WangRight, WangBottom, WangLeft}
+	default:
+		return []WangPosition{WangTop, WangTopRight, WangRight, WangBottomRight, WangBottom, WangBottomLeft, WangLeft, WangTopLeft}
+	}
+}
+
+//AutoTile writes tiles of set, a wang set of tileset, to l whose colors match
+//the colors of grid. Tiles are flipped and rotated if the transformations of
+//the tileset allow it. If multiple tiles match, one is chosen randomly by the
+//probabilities of the tiles and their colors, if no tile matches the tiles
+//with the fewest differences are used. Tiles without any color in grid are
+//not changed. An empty layer is resized to the size of grid.
+func AutoTile(l *Layer, tileset Tileset, set WangSet, grid *WangGrid, opts AutoTileOptions) error {
+	if l.Width == 0 && l.Height == 0 && len(l.Data.DataTiles) == 0 {
+		l.Width = grid.Width
+		l.Height = grid.Height
+		l.Data.DataTiles = make([]DataTile, grid.Width*grid.Height)
+	}
+
+	if l.Width != grid.Width || l.Height != grid.Height || len(l.Data.DataTiles) != l.Width*l.Height {
+		return fmt.Errorf("grid of %dx%d tiles doesn't fit layer %s", grid.Width, grid.Height, l.Name)
+	}
+
+	positions := wangPositions(set.Type)
+	candidates := wangCandidates(tileset, set, positions)
+	if len(candidates) == 0 {
+		return fmt.Errorf("wang set %s contains no tiles", set.Name)
+	}
+
+	random := rand.Float64
+	if opts.Rand != nil {
+		random = opts.Rand.Float64
+	}
+
+	preferUntransformed := tileset.Transformations != nil && tileset.Transformations.PreferUntransformed
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			id := grid.WangID(x, y)
+
+			empty := true
+			for _, position := range positions {
+				if id[position] != 0 {
+					empty = false
+					break
+				}
+			}
+
+			if empty {
+				continue
+			}
+
+			best := bestCandidates(candidates, id, positions, preferUntransformed)
+			l.Data.DataTiles[y*l.Width+x] = chooseCandidate(best, random).tile
+		}
+	}
+
+	return nil
+}
+
+//wangCandidates returns all tiles of set with all allowed transformations
+func wangCandidates(tileset Tileset, set WangSet, positions []WangPosition) []wangCandidate {
+	transforms := allowedTransforms(tileset.Transformations)
+
+	var candidates []wangCandidate
+	for _, wangTile := range set.Tiles {
+		weight := 1.0
+		if tile := tileset.GetTileByID(wangTile.TileID); tile != nil {
+			weight = tile.GetProbability()
+		}
+
+		for _, position := range positions {
+			if color := set.GetColor(wangTile.WangID[position]); color != nil {
+				weight *= color.GetProbability()
+			}
+		}
+
+		for i, transform := range transforms {
+			flips := transform.flips
+			candidates = append(candidates, wangCandidate{
+				tile: DataTile{
+					GID:            tileset.FirstGID + GID(wangTile.TileID),
+					HorizontalFlip: flips&GIDHorizontalFlip != 0,
+					VerticalFlip:   flips&GIDVerticalFlip != 0,
+					DiagonalFlip:   flips&GIDDiagonalFlip != 0,
+				},
+				id:          transform.apply(wangTile.WangID),
+				weight:      weight,
+				transformed: i > 0,
+			})
+		}
+	}
+
+	return candidates
+}
+
+//bestCandidates returns the candidates with the fewest
+//differences to id at the given positions
+func bestCandidates(candidates []wangCandidate, id WangID, positions []WangPosition, preferUntransformed bool) []wangCandidate {
+	var best []wangCandidate
+	fewest := len(positions) + 1
+	for _, candidate := range candidates {
+		differences := 0
+		for _, position := range positions {
+			if candidate.id[position] != id[position] {
+				differences++
+			}
+		}
+
+		if differences < fewest {
+			fewest = differences
+			best = best[:0]
+		}
+
+		if differences == fewest {
+			best = append(best, candidate)
+		}
+	}
+
+	if !preferUntransformed {
+		return best
+	}
+
+	var untransformed []wangCandidate
+	for _, candidate := range best {
+		if !candidate.transformed {
+			untransformed = append(untransformed, candidate)
+		}
+	}
+
+	if len(untransformed) == 0 {
+		return best
+	}
+
+	return untransformed
+}
+
+//chooseCandidate chooses a candidate by its weight,
+//the first one is used if no candidate has a weight
+func chooseCandidate(candidates []wangCandidate, random func() float64) wangCandidate {
+	total := 0.0
+	for _, candidate := range candidates {
+		total += candidate.weight
+	}
+
+	if total <= 0 {
+		return candidates[0]
+	}
+
+	choice := random() * total
+	for _, candidate := range candidates {
+		if choice < candidate.weight {
+			return candidate
+		}
+
+		choice -= candidate.weight
+	}
+
+	return candidates[len(candidates)-1]
+}
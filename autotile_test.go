@@ -0,0 +1,188 @@
+package tmx_test
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test auto tiling", func() {
+	const grass, dirt = 1, 2
+
+	var tileset Tileset
+	var ground, paths WangSet
+	var random *rand.Rand
+
+	BeforeEach(func() {
+		f, err := os.Open("testfiles/wang.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		m, err := NewMap(f)
+		Expect(err).ToNot(HaveOccurred())
+
+		tileset = m.Tilesets[0]
+		ground = tileset.WangSets[0]
+		paths = tileset.WangSets[1]
+		random = rand.New(rand.NewSource(42))
+	})
+
+	gids := func(l Layer) []GID {
+		result := make([]GID, len(l.Data.DataTiles))
+		for i, tile := range l.Data.DataTiles {
+			result[i] = tile.GID
+		}
+
+		return result
+	}
+
+	It("places tiles matching the corners", func() {
+		grid := NewWangGrid(2, 2)
+		for x := 0; x <= 2; x++ {
+			grid.SetCorner(x, 0, grass)
+			grid.SetCorner(x, 1, dirt)
+			grid.SetCorner(x, 2, dirt)
+		}
+
+		var layer Layer
+		Expect(AutoTile(&layer, tileset, ground, grid, AutoTileOptions{Rand: random})).To(Succeed())
+		Expect(layer.Width).To(Equal(2))
+		Expect(layer.Height).To(Equal(2))
+		Expect(gids(layer)).To(Equal([]GID{3, 3, 2, 2}))
+	})
+
+	It("shares corners of painted tiles with their neighbours", func() {
+		grid := NewWangGrid(3, 1)
+		grid.SetTile(0, 0, dirt)
+		grid.SetTile(1, 0, dirt)
+		grid.SetTile(2, 0, dirt)
+		Expect(grid.WangID(1, 0)).To(Equal(WangID{dirt, dirt, dirt, dirt, dirt, dirt, dirt, dirt}))
+
+		grid.SetTile(1, 0, grass)
+		Expect(grid.WangID(0, 0)).To(Equal(WangID{dirt, grass, grass, grass, dirt, dirt, dirt, dirt}))
+		Expect(grid.WangID(3, 0)).To(Equal(WangID{}))
+	})
+
+	It("keeps tiles without colors", func() {
+		layer := Layer{Width: 2, Height: 1, Data: Data{DataTiles: []DataTile{{GID: 100}, {GID: 200}}}}
+		grid := NewWangGrid(2, 1)
+		grid.SetTile(1, 0, grass)
+		grid.SetCorner(1, 0, 0)
+		grid.SetCorner(1, 1, 0)
+		grid.SetCorner(2, 0, grass)
+		grid.SetCorner(2, 1, grass)
+		Expect(AutoTile(&layer, tileset, ground, grid, AutoTileOptions{Rand: random})).To(Succeed())
+		Expect(gids(layer)).To(Equal([]GID{100, 1}))
+	})
+
+	It("matches the edges of edge sets", func() {
+		grid := NewWangGrid(3, 1)
+		grid.SetEdge(1, 0, WangLeft, 1)
+		grid.SetEdge(1, 0, WangRight, 1)
+		grid.SetEdge(1, 0, WangTopLeft, 1)
+		Expect(grid.WangID(1, 0)).To(Equal(WangID{0, 0, 1, 0, 0, 0, 1, 0}))
+
+		var layer Layer
+		Expect(AutoTile(&layer, tileset, paths, grid, AutoTileOptions{Rand: random})).To(Succeed())
+		Expect(gids(layer)).To(Equal([]GID{12, 12, 12}))
+	})
+
+	It("flips and rotates tiles if allowed", func() {
+		grid := NewWangGrid(1, 1)
+		grid.SetCorner(0, 0, grass)
+		grid.SetCorner(0, 1, grass)
+		grid.SetCorner(1, 0, dirt)
+		grid.SetCorner(1, 1, dirt)
+
+		var layer Layer
+		Expect(AutoTile(&layer, tileset, ground, grid, AutoTileOptions{Rand: random})).To(Succeed())
+		Expect(layer.Data.DataTiles[0].HorizontalFlip || layer.Data.DataTiles[0].VerticalFlip || layer.Data.DataTiles[0].DiagonalFlip).To(BeFalse())
+
+		tileset.Transformations = &Transformations{Rotate: true}
+		rotated := []DataTile{
+			{GID: 3, DiagonalFlip: true, VerticalFlip: true},
+			{GID: 4, DiagonalFlip: true, HorizontalFlip: true},
+		}
+		found := map[GID]bool{}
+		for i := 0; i < 20; i++ {
+			layer = Layer{}
+			Expect(AutoTile(&layer, tileset, ground, grid, AutoTileOptions{Rand: random})).To(Succeed())
+			Expect(rotated).To(ContainElement(layer.Data.DataTiles[0]))
+			found[layer.Data.DataTiles[0].GID] = true
+		}
+		Expect(found).To(HaveLen(2))
+	})
+
+	It("prefers untransformed tiles if requested", func() {
+		tileset.Transformations = &Transformations{HFlip: true, VFlip: true, Rotate: true, PreferUntransformed: true}
+		grid := NewWangGrid(1, 1)
+		grid.SetTile(0, 0, grass)
+		grid.SetCorner(0, 1, dirt)
+		grid.SetCorner(1, 1, dirt)
+
+		for i := 0; i < 20; i++ {
+			var layer Layer
+			Expect(AutoTile(&layer, tileset, ground, grid, AutoTileOptions{Rand: random})).To(Succeed())
+			Expect(layer.Data.DataTiles[0]).To(Equal(DataTile{GID: 3}))
+		}
+	})
+
+	It("chooses tiles by their probability", func() {
+		never := 0.0
+		tileset := Tileset{FirstGID: 10, Tiles: []Tile{{ID: 1, Probability: &never}}}
+		set := WangSet{Type: "corner", Colors: []WangColor{{Name: "Grass"}}, Tiles: []WangTile{
+			{TileID: 0, WangID: WangID{0, 1, 0, 1, 0, 1, 0, 1}},
+			{TileID: 1, WangID: WangID{0, 1, 0, 1, 0, 1, 0, 1}},
+		}}
+
+		grid := NewWangGrid(4, 4)
+		for x := 0; x < 4; x++ {
+			for y := 0; y < 4; y++ {
+				grid.SetTile(x, y, grass)
+			}
+		}
+
+		var layer Layer
+		Expect(AutoTile(&layer, tileset, set, grid, AutoTileOptions{Rand: random})).To(Succeed())
+		for _, gid := range gids(layer) {
+			Expect(gid).To(Equal(GID(10)))
+		}
+	})
+
+	It("uses the closest tiles if none matches", func() {
+		grid := NewWangGrid(1, 1)
+		grid.SetTile(0, 0, dirt)
+		grid.SetCorner(0, 0, grass)
+
+		var layer Layer
+		Expect(AutoTile(&layer, tileset, ground, grid, AutoTileOptions{Rand: random})).To(Succeed())
+		Expect([]GID{2, 3}).To(ContainElement(layer.Data.DataTiles[0].GID))
+	})
+
+	It("fails for grids of another size and empty sets", func() {
+		layer := Layer{Name: "Ground", Width: 2, Height: 2, Data: Data{DataTiles: make([]DataTile, 4)}}
+		Expect(AutoTile(&layer, tileset, ground, NewWangGrid(3, 2), AutoTileOptions{})).ToNot(Succeed())
+		Expect(AutoTile(&layer, tileset, WangSet{Name: "Empty"}, NewWangGrid(2, 2), AutoTileOptions{})).ToNot(Succeed())
+	})
+
+	It("keeps transformations when encoding", func() {
+		m := Map{Tilesets: []Tileset{tileset}}
+		m.Tilesets[0].Transformations = &Transformations{HFlip: true, Rotate: true}
+
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`<transformations hflip="1" vflip="0" rotate="1" preferuntransformed="0"></transformations>`))
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Tilesets[0].Transformations).To(Equal(m.Tilesets[0].Transformations))
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		decoded, err = NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Tilesets[0].Transformations).To(Equal(m.Tilesets[0].Transformations))
+	})
+})
@@ -0,0 +1,255 @@
+package tmx
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+)
+
+//CacheOptions limit the size of a CachingLocator,
+//zero values mean no limit
+type CacheOptions struct {
+	//MaxEntries is the maximum number of cached images
+	MaxEntries int
+	//MaxBytes is the maximum estimated memory of all cached images
+	MaxBytes int64
+}
+
+//CacheStats contains usage statistics of a CachingLocator
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+//CachingLocator wraps a ResourceLocator and caches its results.
+//It is safe for concurrent use, concurrent requests of the same
+//resource will only load it once. If limits are given the least
+//recently used resources are evicted.
+type CachingLocator struct {
+	parent   ResourceLocator
+	options  CacheOptions
+	mutex    sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List
+	inFlight map[string]*pendingResource
+	stats    CacheStats
+}
+
+type cachedResource struct {
+	filepath string
+	image    image.Image
+	bytes    int64
+}
+
+//pendingResource is a resource that is currently loading
+type pendingResource struct {
+	done  chan struct{}
+	image image.Image
+	err   error
+}
+
+//NewCachingResourceLocator returns a CachingLocator with the given limits
+func NewCachingResourceLocator(l ResourceLocator, options CacheOptions) *CachingLocator {
+	return &CachingLocator{
+		parent:   l,
+		options:  options,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+		inFlight: map[string]*pendingResource{},
+	}
+}
+
+//LocateResource to implement ResourceLocator interface
+func (c *CachingLocator) LocateResource(filepath string) (image.Image, error) {
+	return c.LocateResourceContext(context.Background(), filepath)
+}
+
+//LocateResourceContext to implement ContextResourceLocator interface,
+//the context is passed to the parent if it supports it. Callers waiting
+//for a resource loaded by another caller retry if only the context of
+//that caller is done.
+func (c *CachingLocator) LocateResourceContext(ctx context.Context, filepath string) (image.Image, error) {
+	return c.locate(ctx, filepath, func(ctx context.Context) (image.Image, error) {
+		return WithContext(c.parent).LocateResourceContext(ctx, filepath)
+	})
+}
+
+//locateConverted returns the image at filepath whose color trans is replaced
+//by alpha. Converted images are cached like all others, the unconverted
+//image is loaded from the parent and not cached.
+func (c *CachingLocator) locateConverted(ctx context.Context, filepath, trans string) (image.Image, error) {
+	return c.locate(ctx, convertedKey(filepath, trans), func(ctx context.Context) (image.Image, error) {
+		img, err := WithContext(c.parent).LocateResourceContext(ctx, filepath)
+		if err != nil {
+			return nil, err
+		}
+
+		return applyTransparency(img, hexcolor(trans)), nil
+	})
+}
+
+//convertedKey returns the cache key of a converted image,
+//it can't collide with paths because they never contain NUL
+func convertedKey(filepath, trans string) string {
+	return filepath + "\x00" + trans
+}
+
+//locate returns the cached image stored under key, it is loaded
+//with load if missing. Concurrent requests only load it once.
+func (c *CachingLocator) locate(
+	ctx context.Context,
+	key string,
+	load func(ctx context.Context) (image.Image, error),
+) (image.Image, error) {
+	c.mutex.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.stats.Hits++
+		c.mutex.Unlock()
+
+		return element.Value.(*cachedResource).image, nil
+	}
+
+	if pending, ok := c.inFlight[key]; ok {
+		c.stats.Hits++
+		c.mutex.Unlock()
+		select {
+		case <-pending.done:
+			//the load was canceled by the context of another caller
+			if isContextError(pending.err) && ctx.Err() == nil {
+				return c.locate(ctx, key, load)
+			}
+
+			return pending.image, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c.stats.Misses++
+	pending := &pendingResource{done: make(chan struct{})}
+	c.inFlight[key] = pending
+	c.mutex.Unlock()
+
+	c.load(ctx, key, pending, load)
+
+	return pending.image, pending.err
+}
+
+//load loads a pending resource and releases all
+//waiting callers, even if the parent panics
+func (c *CachingLocator) load(
+	ctx context.Context,
+	key string,
+	pending *pendingResource,
+	load func(ctx context.Context) (image.Image, error),
+) {
+	//waiters get this error if the parent panics
+	pending.err = fmt.Errorf("loading %q panicked", key)
+	defer func() {
+		c.mutex.Lock()
+		delete(c.inFlight, key)
+		if pending.err == nil {
+			c.add(key, pending.image)
+		}
+		c.mutex.Unlock()
+		close(pending.done)
+	}()
+
+	pending.image, pending.err = load(ctx)
+}
+
+//isContextError returns true if err was caused by a done context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+//UnsetResource removes the resource and all of its
+//converted copies from the cache
+func (c *CachingLocator) UnsetResource(filepath string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	prefix := convertedKey(filepath, "")
+	for key, element := range c.entries {
+		if key == filepath || strings.HasPrefix(key, prefix) {
+			c.remove(element)
+		}
+	}
+}
+
+//Stats returns the current cache statistics
+func (c *CachingLocator) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+
+	return stats
+}
+
+//add stores img and evicts old entries, c.mutex must be locked
+func (c *CachingLocator) add(filepath string, img image.Image) {
+	if element, ok := c.entries[filepath]; ok {
+		c.remove(element)
+	}
+
+	entry := &cachedResource{filepath: filepath, image: img, bytes: estimateImageBytes(img)}
+	c.entries[filepath] = c.order.PushFront(entry)
+	c.stats.Bytes += entry.bytes
+
+	for c.order.Len() > 1 && c.exceedsLimits() {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *CachingLocator) exceedsLimits() bool {
+	if c.options.MaxEntries > 0 && c.order.Len() > c.options.MaxEntries {
+		return true
+	}
+
+	return c.options.MaxBytes > 0 && c.stats.Bytes > c.options.MaxBytes
+}
+
+func (c *CachingLocator) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*cachedResource)
+	delete(c.entries, entry.filepath)
+	c.stats.Bytes -= entry.bytes
+}
+
+//estimateImageBytes returns the approximate memory used by img
+func estimateImageBytes(img image.Image) int64 {
+	switch img := img.(type) {
+	case nil:
+		return 0
+	case *image.RGBA:
+		return int64(len(img.Pix))
+	case *image.NRGBA:
+		return int64(len(img.Pix))
+	case *image.RGBA64:
+		return int64(len(img.Pix))
+	case *image.NRGBA64:
+		return int64(len(img.Pix))
+	case *image.Gray:
+		return int64(len(img.Pix))
+	case *image.Gray16:
+		return int64(len(img.Pix))
+	case *image.Paletted:
+		return int64(len(img.Pix) + len(img.Palette)*4)
+	case *image.YCbCr:
+		return int64(len(img.Y) + len(img.Cb) + len(img.Cr))
+	}
+
+	bounds := img.Bounds()
+
+	return int64(bounds.Dx() * bounds.Dy() * 4)
+}
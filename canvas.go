@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
 )
 
 //Canvas to draw on
@@ -69,3 +70,115 @@ func NewImageCanvasFromMap(m Map) *ImgCanvas {
 
 	return &ic
 }
+
+//NewImageCanvas returns an image canvas with the given bounds,
+//e.g. to render a region of a World
+func NewImageCanvas(bounds image.Rectangle) *ImgCanvas {
+	return &ImgCanvas{target: image.NewRGBA(bounds)}
+}
+
+//offsetCanvas moves everything that is drawn by offset
+type offsetCanvas struct {
+	ImageCanvas
+	offset image.Point
+}
+
+//Bounds returns the moved bounds
+func (c offsetCanvas) Bounds() image.Rectangle {
+	return c.ImageCanvas.Bounds().Sub(c.offset)
+}
+
+//FillRect fills the moved rectangle
+func (c offsetCanvas) FillRect(what color.Color, where image.Rectangle) {
+	c.ImageCanvas.FillRect(what, where.Add(c.offset))
+}
+
+//Draw draws what at the moved rectangle
+func (c offsetCanvas) Draw(what image.Image, where image.Rectangle) {
+	c.ImageCanvas.Draw(what, where.Add(c.offset))
+}
+
+//relativeOffsetCanvas moves all tiles drawn on a RelativeCanvas by offset
+type relativeOffsetCanvas struct {
+	Canvas
+	relative RelativeCanvas
+	offset   image.Point
+}
+
+//Bounds returns the moved bounds
+func (c relativeOffsetCanvas) Bounds() image.Rectangle {
+	return c.Canvas.Bounds().Sub(c.offset)
+}
+
+//FillRect fills the moved rectangle
+func (c relativeOffsetCanvas) FillRect(what color.Color, where image.Rectangle) {
+	c.Canvas.FillRect(what, where.Add(c.offset))
+}
+
+//Draw draws the tile at the moved rectangle
+func (c relativeOffsetCanvas) Draw(tile image.Rectangle, where image.Rectangle, f FlipMode, tileset string) {
+	c.relative.Draw(tile, where.Add(c.offset), f, tileset)
+}
+
+//opacityCanvas draws all images with the given opacity
+type opacityCanvas struct {
+	ImageCanvas
+	opacity float64
+}
+
+//Draw draws a translucent copy of what
+func (c opacityCanvas) Draw(what image.Image, where image.Rectangle) {
+	faded := image.NewRGBA(what.Bounds())
+	mask := image.NewUniform(color.Alpha{A: uint8(math.Round(c.opacity * 0xff))})
+	draw.DrawMask(faded, faded.Bounds(), what, what.Bounds().Min, mask, image.Point{}, draw.Src)
+	c.ImageCanvas.Draw(faded, where)
+}
+
+//clippedCanvas only draws inside of clip
+type clippedCanvas struct {
+	ImageCanvas
+	clip image.Rectangle
+}
+
+//Bounds returns the clipped bounds
+func (c clippedCanvas) Bounds() image.Rectangle {
+	return c.clip
+}
+
+//FillRect fills the clipped part of where
+func (c clippedCanvas) FillRect(what color.Color, where image.Rectangle) {
+	where = where.Intersect(c.clip)
+	if where.Empty() {
+		return
+	}
+
+	c.ImageCanvas.FillRect(what, where)
+}
+
+//Draw draws the clipped part of what
+func (c clippedCanvas) Draw(what image.Image, where image.Rectangle) {
+	clipped := where.Intersect(c.clip)
+	if clipped.Empty() {
+		return
+	}
+
+	min := what.Bounds().Min.Add(clipped.Min.Sub(where.Min))
+	source := image.Rectangle{Min: min, Max: min.Add(clipped.Size())}
+	if s, ok := what.(subImager); ok {
+		what = s.SubImage(source)
+	} else {
+		what = croppedImage{Image: what, bounds: source.Intersect(what.Bounds())}
+	}
+
+	c.ImageCanvas.Draw(what, clipped)
+}
+
+//croppedImage limits the bounds of an image
+type croppedImage struct {
+	image.Image
+	bounds image.Rectangle
+}
+
+func (c croppedImage) Bounds() image.Rectangle {
+	return c.bounds
+}
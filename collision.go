@@ -0,0 +1,332 @@
+package tmx
+
+import (
+	"math"
+	"sort"
+)
+
+//ShapeKind is the kind of a collision shape
+type ShapeKind uint8
+
+const (
+	//ShapeRectangle is a rectangle object
+	ShapeRectangle ShapeKind = iota
+	//ShapeEllipse is an ellipse object
+	ShapeEllipse
+	//ShapePolygon is a closed polygon object
+	ShapePolygon
+	//ShapePolyline is an open polyline object
+	ShapePolyline
+	//ShapePoint is a point object
+	ShapePoint
+)
+
+func (s ShapeKind) String() string {
+	switch s {
+	case ShapeRectangle:
+		return "Rectangle"
+	case ShapeEllipse:
+		return "Ellipse"
+	case ShapePolygon:
+		return "Polygon"
+	case ShapePolyline:
+		return "Polyline"
+	case ShapePoint:
+		return "Point"
+	default:
+		return "Unknown"
+	}
+}
+
+//CollisionShape is a collision object of a tile in world space
+type CollisionShape struct {
+	Kind ShapeKind
+	//Vertices of the shape in pixels, rectangles and ellipses
+	//contain the four corners of their bounding box
+	Vertices []Vertex
+	//X and Y are the position of the tile in the layer
+	X, Y int
+	//Object is the object of the tileset that defines the
+	//shape, it is empty for merged rectangles
+	Object Object
+}
+
+//Bounds returns the axis aligned bounding box of the shape
+func (s CollisionShape) Bounds() (min, max Vertex) {
+	for i, v := range s.Vertices {
+		if i == 0 {
+			min, max = v, v
+			continue
+		}
+
+		min.X = math.Min(min.X, v.X)
+		min.Y = math.Min(min.Y, v.Y)
+		max.X = math.Max(max.X, v.X)
+		max.Y = math.Max(max.Y, v.Y)
+	}
+
+	return min, max
+}
+
+//CollisionOptions configure the collision shapes of a layer
+type CollisionOptions struct {
+	//MergeRectangles combines adjacent axis aligned rectangles
+	//of neighbouring tiles into larger rectangles
+	MergeRectangles bool
+}
+
+//CollisionShapes returns the collision shapes of all tiles in l, these are
+//the objects of the tiles' object groups moved to the position of the tile.
+//Tile flips and tile offsets of the tilesets are applied. If rectangles
+//are merged they are returned after all other shapes sorted by position.
+func (m Map) CollisionShapes(l Layer, opts CollisionOptions) ([]CollisionShape, error) {
+	var shapes []CollisionShape
+	for i, dt := range l.Data.DataTiles {
+		if dt.GID == 0 || l.Width == 0 {
+			continue
+		}
+
+		tileset, err := m.GetTilesetForGID(dt.GID)
+		if err != nil {
+			return nil, err
+		}
+
+		tile := tileset.GetTileByID(uint32(dt.GID - tileset.FirstGID))
+		if tile == nil || tile.ObjectGroup == nil {
+			continue
+		}
+
+		width, height := tileset.TileWidth, tileset.TileHeight
+		if tileset.IsCollection() {
+			width, height = tile.Image.Width, tile.Image.Height
+		}
+
+		//tiles are aligned to the bottom left corner of their cell
+		x, y := i%l.Width, i/l.Width
+		origin := Vertex{
+			X: float64(x * m.TileWidth),
+			Y: float64(y*m.TileHeight + m.TileHeight - height),
+		}
+
+		if tileset.TileOffset != nil {
+			origin.X += float64(tileset.TileOffset.X)
+			origin.Y += float64(tileset.TileOffset.Y)
+		}
+
+		for _, object := range tile.ObjectGroup.Objects {
+			shape, err := object.collisionShape()
+			if err != nil {
+				return nil, err
+			}
+
+			for j, v := range shape.Vertices {
+				v = dt.flipVertex(v, float64(width), float64(height))
+				shape.Vertices[j] = Vertex{X: origin.X + v.X, Y: origin.Y + v.Y}
+			}
+
+			shape.X, shape.Y = x, y
+			shapes = append(shapes, shape)
+		}
+	}
+
+	if opts.MergeRectangles {
+		shapes = mergeRectangles(shapes)
+	}
+
+	return shapes, nil
+}
+
+//collisionShape returns the shape of o relative to its tile
+func (o Object) collisionShape() (CollisionShape, error) {
+	shape := CollisionShape{Kind: ShapeRectangle, Object: o}
+
+	var vertices []Vertex
+	var err error
+	switch {
+	case o.Point != nil:
+		shape.Kind = ShapePoint
+		vertices = []Vertex{{}}
+	case len(o.Polygons) > 0:
+		shape.Kind = ShapePolygon
+		vertices, err = o.Polygons[0].Vertices()
+	case len(o.PolyLines) > 0:
+		shape.Kind = ShapePolyline
+		vertices, err = o.PolyLines[0].Vertices()
+	default:
+		if o.Ellipse != nil {
+			shape.Kind = ShapeEllipse
+		}
+
+		//the position of tile objects is their bottom left corner
+		top := 0.0
+		if o.GID != 0 {
+			top = -o.Height
+		}
+
+		vertices = []Vertex{
+			{X: 0, Y: top},
+			{X: o.Width, Y: top},
+			{X: o.Width, Y: top + o.Height},
+			{X: 0, Y: top + o.Height},
+		}
+	}
+
+	if err != nil {
+		return shape, err
+	}
+
+	//rotation is given clockwise in degrees around the object position
+	sin, cos := math.Sincos(o.Rotation * math.Pi / 180)
+	for _, v := range vertices {
+		if o.Rotation != 0 {
+			v = Vertex{X: v.X*cos - v.Y*sin, Y: v.X*sin + v.Y*cos}
+		}
+
+		shape.Vertices = append(shape.Vertices, Vertex{X: o.X + v.X, Y: o.Y + v.Y})
+	}
+
+	return shape, nil
+}
+
+//flipVertex applies the flips of d to v inside a tile of the given size,
+//the diagonal flip is applied first like Tiled does
+func (d DataTile) flipVertex(v Vertex, width, height float64) Vertex {
+	if d.DiagonalFlip {
+		v.X, v.Y = v.Y, v.X
+		width, height = height, width
+	}
+
+	if d.HorizontalFlip {
+		v.X = width - v.X
+	}
+
+	if d.VerticalFlip {
+		v.Y = height - v.Y
+	}
+
+	return v
+}
+
+//collisionEpsilon is the tolerance used to compare coordinates
+const collisionEpsilon = 1e-6
+
+func nearlyEqual(a, b float64) bool {
+	return math.Abs(a-b) < collisionEpsilon
+}
+
+//collisionBox is a mergeable rectangle
+type collisionBox struct {
+	min, max Vertex
+	shape    CollisionShape
+	merged   bool
+}
+
+//isAxisAligned returns true if the shape is a rectangle
+//that can be described by its bounding box
+func (s CollisionShape) isAxisAligned() bool {
+	if s.Kind != ShapeRectangle || len(s.Vertices) != 4 {
+		return false
+	}
+
+	min, max := s.Bounds()
+	for _, v := range s.Vertices {
+		if !(nearlyEqual(v.X, min.X) || nearlyEqual(v.X, max.X)) ||
+			!(nearlyEqual(v.Y, min.Y) || nearlyEqual(v.Y, max.Y)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//mergeRectangles merges adjacent rectangles of the same height into
+//rows first and rows of the same width into columns afterwards
+func mergeRectangles(shapes []CollisionShape) []CollisionShape {
+	var result []CollisionShape
+	var boxes []collisionBox
+	for _, shape := range shapes {
+		if !shape.isAxisAligned() {
+			result = append(result, shape)
+			continue
+		}
+
+		min, max := shape.Bounds()
+		boxes = append(boxes, collisionBox{min: min, max: max, shape: shape})
+	}
+
+	boxes = mergeBoxes(boxes, func(b collisionBox) (float64, float64, float64) {
+		return b.min.Y, b.max.Y, b.min.X
+	}, func(a, b collisionBox) bool {
+		return nearlyEqual(a.min.Y, b.min.Y) && nearlyEqual(a.max.Y, b.max.Y) && nearlyEqual(a.max.X, b.min.X)
+	})
+
+	boxes = mergeBoxes(boxes, func(b collisionBox) (float64, float64, float64) {
+		return b.min.X, b.max.X, b.min.Y
+	}, func(a, b collisionBox) bool {
+		return nearlyEqual(a.min.X, b.min.X) && nearlyEqual(a.max.X, b.max.X) && nearlyEqual(a.max.Y, b.min.Y)
+	})
+
+	sort.SliceStable(boxes, func(i, j int) bool {
+		if boxes[i].min.Y != boxes[j].min.Y {
+			return boxes[i].min.Y < boxes[j].min.Y
+		}
+
+		return boxes[i].min.X < boxes[j].min.X
+	})
+
+	for _, box := range boxes {
+		shape := box.shape
+		if box.merged {
+			shape = CollisionShape{
+				Kind: ShapeRectangle,
+				Vertices: []Vertex{
+					box.min,
+					{X: box.max.X, Y: box.min.Y},
+					box.max,
+					{X: box.min.X, Y: box.max.Y},
+				},
+				X: box.shape.X,
+				Y: box.shape.Y,
+			}
+		}
+
+		result = append(result, shape)
+	}
+
+	return result
+}
+
+//mergeBoxes sorts boxes by the given keys and merges every box
+//into its predecessor if they are adjacent
+func mergeBoxes(
+	boxes []collisionBox,
+	keys func(collisionBox) (float64, float64, float64),
+	adjacent func(a, b collisionBox) bool,
+) []collisionBox {
+	sort.SliceStable(boxes, func(i, j int) bool {
+		a1, a2, a3 := keys(boxes[i])
+		b1, b2, b3 := keys(boxes[j])
+		if a1 != b1 {
+			return a1 < b1
+		}
+
+		if a2 != b2 {
+			return a2 < b2
+		}
+
+		return a3 < b3
+	})
+
+	var result []collisionBox
+	for _, box := range boxes {
+		if last := len(result) - 1; last >= 0 && adjacent(result[last], box) {
+			result[last].max = box.max
+			result[last].merged = true
+			continue
+		}
+
+		result = append(result, box)
+	}
+
+	return result
+}
@@ -0,0 +1,130 @@
+package tmx_test
+
+import (
+	"bytes"
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test tile collision shapes", func() {
+	var m *Map
+
+	BeforeEach(func() {
+		f, err := os.Open("testfiles/collision.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		m, err = NewMap(f)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	rectangle := func(x1, y1, x2, y2 float64) []Vertex {
+		return []Vertex{{X: x1, Y: y1}, {X: x2, Y: y1}, {X: x2, Y: y2}, {X: x1, Y: y2}}
+	}
+
+	expectVertices := func(actual, expected []Vertex) {
+		Expect(actual).To(HaveLen(len(expected)))
+		for i := range expected {
+			Expect(actual[i].X).To(BeNumerically("~", expected[i].X, 1e-9))
+			Expect(actual[i].Y).To(BeNumerically("~", expected[i].Y, 1e-9))
+		}
+	}
+
+	It("loads the object groups of tiles", func() {
+		tile := m.Tilesets[0].GetTileByID(3)
+		Expect(tile.ObjectGroup).ToNot(BeNil())
+		Expect(tile.ObjectGroup.Objects).To(HaveLen(2))
+		Expect(tile.ObjectGroup.Objects[0].Ellipse).ToNot(BeNil())
+		Expect(tile.ObjectGroup.Objects[0].Width).To(Equal(6.0))
+		Expect(tile.ObjectGroup.Objects[1].Point).ToNot(BeNil())
+		Expect(m.Tilesets[0].TileOffset).To(BeNil())
+		Expect(m.Tilesets[1].TileOffset).To(Equal(&TileOffset{X: 4, Y: -2}))
+		Expect(m.Tilesets[1].Tiles[0].ObjectGroup.Objects[0].Rotation).To(Equal(90.0))
+	})
+
+	It("returns shapes in world space", func() {
+		shapes, err := m.CollisionShapes(m.Layers[0], CollisionOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		expected := []struct {
+			kind     ShapeKind
+			x, y     int
+			name     string
+			vertices []Vertex
+		}{
+			{ShapeRectangle, 0, 0, "solid", rectangle(0, 0, 16, 16)},
+			{ShapeRectangle, 1, 0, "solid", rectangle(16, 0, 32, 16)},
+			{ShapePolygon, 2, 0, "slope", []Vertex{{X: 48, Y: 16}, {X: 32, Y: 0}, {X: 32, Y: 16}}},
+			{ShapeRectangle, 3, 0, "solid", rectangle(48, 0, 64, 16)},
+			{ShapeRectangle, 4, 0, "pole", []Vertex{{X: 76, Y: -2}, {X: 76, Y: 6}, {X: 72, Y: 6}, {X: 72, Y: -2}}},
+			{ShapeRectangle, 0, 1, "half", rectangle(0, 24, 16, 32)},
+			{ShapeRectangle, 1, 1, "half", rectangle(16, 24, 32, 32)},
+			{ShapeEllipse, 2, 1, "rock", []Vertex{{X: 36, Y: 18}, {X: 36, Y: 24}, {X: 44, Y: 24}, {X: 44, Y: 18}}},
+			{ShapePoint, 2, 1, "spawn", []Vertex{{X: 34, Y: 24}}},
+			{ShapeRectangle, 3, 1, "solid", rectangle(48, 16, 64, 32)},
+			{ShapeRectangle, 4, 1, "half", []Vertex{{X: 64, Y: 24}, {X: 80, Y: 24}, {X: 80, Y: 16}, {X: 64, Y: 16}}},
+		}
+
+		Expect(shapes).To(HaveLen(len(expected)))
+		for i, e := range expected {
+			Expect(shapes[i].Kind).To(Equal(e.kind), e.name)
+			Expect(shapes[i].X).To(Equal(e.x))
+			Expect(shapes[i].Y).To(Equal(e.y))
+			Expect(shapes[i].Object.Name).To(Equal(e.name))
+			expectVertices(shapes[i].Vertices, e.vertices)
+		}
+
+		min, max := shapes[4].Bounds()
+		Expect(min.X).To(BeNumerically("~", 72, 1e-9))
+		Expect(min.Y).To(BeNumerically("~", -2, 1e-9))
+		Expect(max.X).To(BeNumerically("~", 76, 1e-9))
+		Expect(max.Y).To(BeNumerically("~", 6, 1e-9))
+	})
+
+	It("merges adjacent rectangles", func() {
+		shapes, err := m.CollisionShapes(m.Layers[0], CollisionOptions{MergeRectangles: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shapes).To(HaveLen(8))
+
+		Expect(shapes[0].Kind).To(Equal(ShapePolygon))
+		Expect(shapes[1].Kind).To(Equal(ShapeEllipse))
+		Expect(shapes[2].Kind).To(Equal(ShapePoint))
+
+		//the rotated rectangle is axis aligned but has no neighbours
+		Expect(shapes[3].Object.Name).To(Equal("pole"))
+
+		Expect(shapes[4].Vertices).To(Equal(rectangle(0, 0, 32, 16)))
+		Expect(shapes[4].Object).To(BeZero())
+		Expect(shapes[5].Vertices).To(Equal(rectangle(48, 0, 64, 32)))
+		Expect([]int{shapes[5].X, shapes[5].Y}).To(Equal([]int{3, 0}))
+		Expect(shapes[6].Object.Name).To(Equal("half"))
+		Expect([]int{shapes[6].X, shapes[6].Y}).To(Equal([]int{4, 1}))
+		Expect(shapes[7].Vertices).To(Equal(rectangle(0, 24, 32, 32)))
+	})
+
+	It("keeps the object groups of tiles when encoding", func() {
+		expected, err := m.CollisionShapes(m.Layers[0], CollisionOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.CollisionShapes(decoded.Layers[0], CollisionOptions{})).To(Equal(expected))
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		decoded, err = NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Tilesets[1].TileOffset).To(Equal(&TileOffset{X: 4, Y: -2}))
+		Expect(decoded.CollisionShapes(decoded.Layers[0], CollisionOptions{})).To(Equal(expected))
+	})
+
+	It("fails for invalid shapes", func() {
+		m.Tilesets[0].Tiles[2].ObjectGroup.Objects[0].Polygons[0].Points = "0,16 16"
+		_, err := m.CollisionShapes(m.Layers[0], CollisionOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+})
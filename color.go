@@ -15,9 +15,20 @@ const (
 
 //implement image.Color interface
 func (c hexcolor) RGBA() (r, g, b, a uint32) {
+	rx, gx, bx, ok := c.parse()
+	if !ok {
+		return defaultRed, defaultBlue, defaultGreen, defaultAlpha
+	}
+
+	return uint32(rx), uint32(gx), uint32(bx), 255
+}
+
+//parse returns the color components, ok is false
+//if the color is not a valid hex color
+func (c hexcolor) parse() (r, g, b uint8, ok bool) {
 	data := []byte(string(c))
 	if len(data) == 0 {
-		return defaultRed, defaultBlue, defaultGreen, defaultAlpha
+		return
 	}
 
 	if data[0] == '#' {
@@ -25,23 +36,23 @@ func (c hexcolor) RGBA() (r, g, b, a uint32) {
 	}
 
 	if len(data) != 6 {
-		return defaultRed, defaultBlue, defaultGreen, defaultAlpha
+		return
 	}
 
-	rx, err := strconv.ParseInt(string(data[0:2]), 16, 0)
+	rx, err := strconv.ParseUint(string(data[0:2]), 16, 8)
 	if err != nil {
-		return defaultRed, defaultBlue, defaultGreen, defaultAlpha
+		return
 	}
 
-	gx, err := strconv.ParseInt(string(data[2:4]), 16, 0)
+	gx, err := strconv.ParseUint(string(data[2:4]), 16, 8)
 	if err != nil {
-		return defaultRed, defaultBlue, defaultGreen, defaultAlpha
+		return
 	}
 
-	bx, err := strconv.ParseInt(string(data[4:6]), 16, 0)
+	bx, err := strconv.ParseUint(string(data[4:6]), 16, 8)
 	if err != nil {
-		return defaultRed, defaultBlue, defaultGreen, defaultAlpha
+		return
 	}
 
-	return uint32(rx), uint32(gx), uint32(bx), 255
+	return uint8(rx), uint8(gx), uint8(bx), true
 }
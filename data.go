@@ -8,6 +8,10 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 //Data contains raw loaded tmx data
@@ -36,11 +40,24 @@ func isDiagonallyFlipped(gid GID) bool {
 // loadEncodedTiles loads all GID informations
 // from RawData to `DataTiles`
 func (d *Data) loadEncodedTiles() error {
+	if d.Encoding == "" {
+		//tiles are stored as xml elements
+		for i, tile := range d.DataTiles {
+			d.DataTiles[i] = newDataTile(tile.GID)
+		}
+
+		return nil
+	}
+
 	if len(d.RawData) == 0 {
 		return nil
 	}
 
 	rawData := bytes.TrimSpace(d.RawData)
+	if d.Encoding == "csv" {
+		return d.loadCSVTiles(rawData)
+	}
+
 	bytesReader := bytes.NewReader(rawData)
 
 	var reader io.Reader
@@ -68,22 +85,48 @@ func (d *Data) loadEncodedTiles() error {
 			GID(decodedData[j+2])<<16 +
 			GID(decodedData[j+3])<<24
 
-		tile := DataTile{}
-		tile.HorizontalFlip = isHorizontallyFlipped(gid)
-		tile.VerticalFlip = isVerticallyFlipped(gid)
-		tile.DiagonalFlip = isDiagonallyFlipped(gid)
+		d.DataTiles[j/4] = newDataTile(gid)
 
-		//flip information must be cleared
-		tile.GID = gid &^ GIDFlips
+		j += 4
+	}
 
-		d.DataTiles[j/4] = tile
+	return nil
+}
 
-		j += 4
+// loadCSVTiles loads all comma separated GIDs from rawData
+func (d *Data) loadCSVTiles(rawData []byte) error {
+	fields := strings.Split(string(rawData), ",")
+	d.DataTiles = make([]DataTile, 0, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		gid, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return err
+		}
+
+		d.DataTiles = append(d.DataTiles, newDataTile(GID(gid)))
 	}
 
 	return nil
 }
 
+// newDataTile splits the flip information from gid
+func newDataTile(gid GID) DataTile {
+	tile := DataTile{}
+	tile.HorizontalFlip = isHorizontallyFlipped(gid)
+	tile.VerticalFlip = isVerticallyFlipped(gid)
+	tile.DiagonalFlip = isDiagonallyFlipped(gid)
+
+	//flip information must be cleared
+	tile.GID = gid &^ GIDFlips
+
+	return tile
+}
+
 // decompress input from `r` with the given `compression` standard
 func decompress(r io.Reader, compression string) (data []byte, err error) {
 	var compressionReader io.Reader
@@ -98,10 +141,18 @@ func decompress(r io.Reader, compression string) (data []byte, err error) {
 		if err != nil {
 			return
 		}
+	case "zstd":
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(r)
+		if err != nil {
+			return
+		}
+		defer decoder.Close()
+		compressionReader = decoder
 	case "":
 		compressionReader = r
 	default:
-		err = errors.New("Only zlib and gzip compressions are supported")
+		err = errors.New("Only zlib, gzip and zstd compressions are supported")
 		return
 	}
 
@@ -0,0 +1,332 @@
+package tmx
+
+import (
+	"fmt"
+	"image"
+)
+
+//index returns the index of the tile at x, y in DataTiles
+func (l Layer) index(x, y int) (int, error) {
+	if x < 0 || y < 0 || x >= l.Width || y*l.Width+x >= len(l.Data.DataTiles) {
+		return 0, fmt.Errorf("position %d,%d is outside of layer %s", x, y, l.Name)
+	}
+
+	return y*l.Width + x, nil
+}
+
+//TileAt returns the tile at position x, y
+func (l Layer) TileAt(x, y int) (DataTile, error) {
+	i, err := l.index(x, y)
+	if err != nil {
+		return DataTile{}, err
+	}
+
+	return l.Data.DataTiles[i], nil
+}
+
+//SetTile sets the tile at position x, y, flips is a combination
+//of GIDHorizontalFlip, GIDVerticalFlip and GIDDiagonalFlip
+func (l *Layer) SetTile(x, y int, gid, flips GID) error {
+	i, err := l.index(x, y)
+	if err != nil {
+		return err
+	}
+
+	l.Data.DataTiles[i] = newDataTile(gid&^GIDFlips | flips&GIDFlips)
+	return nil
+}
+
+//Fill sets all tiles of the layer
+func (l *Layer) Fill(gid, flips GID) {
+	l.FillRect(image.Rect(0, 0, l.Width, l.Height), gid, flips)
+}
+
+//FillRect sets all tiles inside of r, given in tiles,
+//parts of r outside of the layer are ignored
+func (l *Layer) FillRect(r image.Rectangle, gid, flips GID) {
+	r = r.Intersect(image.Rect(0, 0, l.Width, l.Height))
+	tile := newDataTile(gid&^GIDFlips | flips&GIDFlips)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if i, err := l.index(x, y); err == nil {
+				l.Data.DataTiles[i] = tile
+			}
+		}
+	}
+}
+
+//FloodFill replaces the tile at x, y and all tiles connected to it
+//horizontally or vertically that are equal to it, including their flips
+func (l *Layer) FloodFill(x, y int, gid, flips GID) error {
+	start, err := l.index(x, y)
+	if err != nil {
+		return err
+	}
+
+	tile := newDataTile(gid&^GIDFlips | flips&GIDFlips)
+	replaced := l.Data.DataTiles[start]
+	if replaced == tile {
+		return nil
+	}
+
+	l.Data.DataTiles[start] = tile
+	queue := []image.Point{{X: x, Y: y}}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		for _, neighbour := range []image.Point{{X: p.X + 1, Y: p.Y}, {X: p.X - 1, Y: p.Y}, {X: p.X, Y: p.Y + 1}, {X: p.X, Y: p.Y - 1}} {
+			i, err := l.index(neighbour.X, neighbour.Y)
+			if err != nil || l.Data.DataTiles[i] != replaced {
+				continue
+			}
+
+			l.Data.DataTiles[i] = tile
+			queue = append(queue, neighbour)
+		}
+	}
+
+	return nil
+}
+
+//GetLayerByName returns the first tile layer with the given name,
+//layers in groups are searched in the order they are drawn
+func (m *Map) GetLayerByName(name string) *Layer {
+	for _, l := range m.AllLayers() {
+		if l.Name == name {
+			return l
+		}
+	}
+
+	return nil
+}
+
+//AddLayer appends an empty tile layer of the map's size,
+//the returned layer is only valid until layers are changed again
+func (m *Map) AddLayer(name string) *Layer {
+	m.Layers = append(m.Layers, Layer{
+		Name:   name,
+		Width:  m.Width,
+		Height: m.Height,
+		Data:   Data{DataTiles: make([]DataTile, m.Width*m.Height)},
+	})
+
+	return &m.Layers[len(m.Layers)-1]
+}
+
+//InsertLayer inserts l before the tile layer at index of AllLayers into
+//the same map or group, layers with a higher index are drawn above lower
+//ones. l is inserted after the last tile layer if index is the number
+//of tile layers.
+func (m *Map) InsertLayer(index int, l Layer) error {
+	slots := m.layerSlots()
+	if index < 0 || index > len(slots) {
+		return fmt.Errorf("invalid layer index %d", index)
+	}
+
+	switch {
+	case len(slots) == 0:
+		l.position = 0
+		m.Layers = append(m.Layers, l)
+	case index == len(slots):
+		//l is drawn right after the last layer
+		last := slots[index-1]
+		l.position = 0
+		insertLayer(last.layers, last.index+1, l)
+	default:
+		//l is drawn right before the layer at index
+		l.position = slots[index].layer().position
+		insertLayer(slots[index].layers, slots[index].index, l)
+	}
+
+	return nil
+}
+
+//RemoveLayer removes the tile layer at index of AllLayers
+func (m *Map) RemoveLayer(index int) error {
+	slots := m.layerSlots()
+	if index < 0 || index >= len(slots) {
+		return fmt.Errorf("invalid layer index %d", index)
+	}
+
+	removeLayer(slots[index].layers, slots[index].index)
+	return nil
+}
+
+//MoveLayer moves the tile layer at index from of AllLayers to index to,
+//the layer takes the place of the layer at to, which may be in another group
+func (m *Map) MoveLayer(from, to int) error {
+	slots := m.layerSlots()
+	if from < 0 || from >= len(slots) || to < 0 || to >= len(slots) {
+		return fmt.Errorf("can't move layer %d to %d", from, to)
+	}
+
+	source, target := slots[from], slots[to]
+	if source.layers == target.layers {
+		moveLayer(*source.layers, source.index, target.index)
+		return nil
+	}
+
+	l := *source.layer()
+	removeLayer(source.layers, source.index)
+	if from < to {
+		//drawn right after the layer at to
+		l.position = 0
+		insertLayer(target.layers, target.index+1, l)
+	} else {
+		l.position = target.layer().position
+		insertLayer(target.layers, target.index, l)
+	}
+
+	return nil
+}
+
+//insertLayer inserts l into layers at index
+func insertLayer(layers *[]Layer, index int, l Layer) {
+	*layers = append(*layers, Layer{})
+	copy((*layers)[index+1:], (*layers)[index:])
+	(*layers)[index] = l
+}
+
+//removeLayer removes the layer at index from layers
+func removeLayer(layers *[]Layer, index int) {
+	*layers = append((*layers)[:index], (*layers)[index+1:]...)
+}
+
+//moveLayer moves the layer at index from to index to, the positions
+//stay in place so the layer is drawn where the layer at to was
+func moveLayer(layers []Layer, from, to int) {
+	positions := make([]int, len(layers))
+	for i, l := range layers {
+		positions[i] = l.position
+	}
+
+	l := layers[from]
+	if from < to {
+		copy(layers[from:to], layers[from+1:to+1])
+	} else {
+		copy(layers[to+1:from+1], layers[to:from])
+	}
+	layers[to] = l
+
+	for i := range layers {
+		layers[i].position = positions[i]
+	}
+}
+
+//NextFirstGID returns the first gid that is not used by any tileset
+func (m Map) NextFirstGID() GID {
+	next := GID(1)
+	for _, tileset := range m.Tilesets {
+		if end := tileset.FirstGID + GID(tileset.GetNumTiles()); end > next {
+			next = end
+		}
+	}
+
+	return next
+}
+
+//AddTileset appends t after all other tilesets, its FirstGID is set to the
+//next free gid. The index is rebuilt and the FirstGID is returned.
+func (m *Map) AddTileset(t Tileset) GID {
+	t.FirstGID = m.NextFirstGID()
+	m.Tilesets = append(m.Tilesets, t)
+	m.BuildIndex()
+
+	return t.FirstGID
+}
+
+//Anchor is the position of the existing tiles when a map is resized
+type Anchor uint8
+
+const (
+	//AnchorTopLeft keeps the tiles at the top left corner
+	AnchorTopLeft Anchor = iota
+	//AnchorTop keeps the tiles centered at the top edge
+	AnchorTop
+	//AnchorTopRight keeps the tiles at the top right corner
+	AnchorTopRight
+	//AnchorLeft keeps the tiles centered at the left edge
+	AnchorLeft
+	//AnchorCenter keeps the tiles in the center
+	AnchorCenter
+	//AnchorRight keeps the tiles centered at the right edge
+	AnchorRight
+	//AnchorBottomLeft keeps the tiles at the bottom left corner
+	AnchorBottomLeft
+	//AnchorBottom keeps the tiles centered at the bottom edge
+	AnchorBottom
+	//AnchorBottomRight keeps the tiles at the bottom right corner
+	AnchorBottomRight
+)
+
+//offset returns the offset of the existing tiles
+//when resizing from the old to the new size
+func (a Anchor) offset(oldSize, newSize image.Point) image.Point {
+	var offset image.Point
+	switch a % 3 {
+	case 1:
+		offset.X = (newSize.X - oldSize.X) / 2
+	case 2:
+		offset.X = newSize.X - oldSize.X
+	}
+
+	switch a / 3 {
+	case 1:
+		offset.Y = (newSize.Y - oldSize.Y) / 2
+	case 2:
+		offset.Y = newSize.Y - oldSize.Y
+	}
+
+	return offset
+}
+
+//Resize changes the size of the map and all of its tile layers, the
+//existing tiles are placed at anchor. Objects are moved by the same
+//offset, they are kept even if they are outside of the map afterwards.
+func (m *Map) Resize(width, height int, anchor Anchor) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid map size %dx%d", width, height)
+	}
+
+	offset := anchor.offset(image.Pt(m.Width, m.Height), image.Pt(width, height))
+	m.forEachLayer(func(l *Layer) error {
+		l.resize(width, height, offset)
+		return nil
+	})
+
+	m.forEachObjectGroup(func(g *ObjectGroup) error {
+		for j := range g.Objects {
+			object := &g.Objects[j]
+			object.X += float64(offset.X * m.TileWidth)
+			object.Y += float64(offset.Y * m.TileHeight)
+		}
+
+		return nil
+	})
+
+	m.Width = width
+	m.Height = height
+
+	return nil
+}
+
+//resize changes the size of l, existing tiles are moved by offset
+func (l *Layer) resize(width, height int, offset image.Point) {
+	tiles := make([]DataTile, width*height)
+	for y := 0; y < l.Height; y++ {
+		for x := 0; x < l.Width; x++ {
+			tile, err := l.TileAt(x, y)
+			tx, ty := x+offset.X, y+offset.Y
+			if err != nil || tx < 0 || ty < 0 || tx >= width || ty >= height {
+				continue
+			}
+
+			tiles[ty*width+tx] = tile
+		}
+	}
+
+	l.Width = width
+	l.Height = height
+	l.Data.DataTiles = tiles
+}
@@ -0,0 +1,235 @@
+package tmx_test
+
+import (
+	"bytes"
+	"image"
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test map editing", func() {
+	gids := func(l Layer) []GID {
+		result := make([]GID, len(l.Data.DataTiles))
+		for i, tile := range l.Data.DataTiles {
+			result[i] = tile.GID
+		}
+
+		return result
+	}
+
+	Context("Test tiles of layers", func() {
+		var m Map
+		var layer *Layer
+
+		BeforeEach(func() {
+			m = Map{Width: 4, Height: 3, TileWidth: 32, TileHeight: 32}
+			layer = m.AddLayer("Ground")
+		})
+
+		It("sets single tiles", func() {
+			Expect(layer.SetTile(1, 2, 7, GIDHorizontalFlip|GIDDiagonalFlip)).To(Succeed())
+			tile, err := layer.TileAt(1, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tile).To(Equal(DataTile{GID: 7, HorizontalFlip: true, DiagonalFlip: true}))
+			Expect(layer.Data.DataTiles[9]).To(Equal(tile))
+
+			Expect(layer.SetTile(2, 0, 8|GIDVerticalFlip, 0)).To(Succeed())
+			Expect(layer.TileAt(2, 0)).To(Equal(DataTile{GID: 8}))
+
+			for _, position := range []image.Point{{X: -1, Y: 0}, {X: 4, Y: 0}, {X: 0, Y: 3}} {
+				Expect(layer.SetTile(position.X, position.Y, 1, 0)).ToNot(Succeed())
+				_, err := layer.TileAt(position.X, position.Y)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+
+		It("fills rectangles", func() {
+			layer.Fill(1, 0)
+			Expect(gids(*layer)).To(Equal([]GID{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
+
+			layer.FillRect(image.Rect(2, 1, 10, 10), 2, GIDVerticalFlip)
+			Expect(gids(*layer)).To(Equal([]GID{1, 1, 1, 1, 1, 1, 2, 2, 1, 1, 2, 2}))
+			Expect(layer.Data.DataTiles[11].VerticalFlip).To(BeTrue())
+
+			layer.FillRect(image.Rect(-5, -5, -1, -1), 3, 0)
+			Expect(gids(*layer)).To(Equal([]GID{1, 1, 1, 1, 1, 1, 2, 2, 1, 1, 2, 2}))
+		})
+
+		It("flood fills connected tiles", func() {
+			layer.Fill(1, 0)
+			layer.FillRect(image.Rect(2, 0, 3, 3), 2, 0)
+			Expect(layer.SetTile(3, 1, 1, GIDHorizontalFlip)).To(Succeed())
+
+			Expect(layer.FloodFill(0, 0, 5, 0)).To(Succeed())
+			Expect(gids(*layer)).To(Equal([]GID{5, 5, 2, 1, 5, 5, 2, 1, 5, 5, 2, 1}))
+
+			//tiles with other flips are not replaced
+			Expect(layer.FloodFill(3, 0, 6, 0)).To(Succeed())
+			Expect(gids(*layer)).To(Equal([]GID{5, 5, 2, 6, 5, 5, 2, 1, 5, 5, 2, 1}))
+
+			Expect(layer.FloodFill(2, 2, 2, 0)).To(Succeed())
+			Expect(gids(*layer)).To(Equal([]GID{5, 5, 2, 6, 5, 5, 2, 1, 5, 5, 2, 1}))
+
+			Expect(layer.FloodFill(4, 0, 1, 0)).ToNot(Succeed())
+		})
+	})
+
+	Context("Test layers and tilesets", func() {
+		var m *Map
+
+		BeforeEach(func() {
+			f, err := os.Open("testfiles/simple_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			m, err = NewMap(f)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		names := func() []string {
+			var result []string
+			for _, l := range m.Layers {
+				result = append(result, l.Name)
+			}
+
+			return result
+		}
+
+		It("adds, removes and moves layers", func() {
+			Expect(names()).To(Equal([]string{"Floor", "Below", "Above"}))
+
+			added := m.AddLayer("Top")
+			Expect(added.Width).To(Equal(24))
+			Expect(added.Data.DataTiles).To(HaveLen(24 * 24))
+			Expect(m.GetLayerByName("Top")).To(BeIdenticalTo(&m.Layers[3]))
+			Expect(m.GetLayerByName("Missing")).To(BeNil())
+
+			Expect(m.InsertLayer(0, Layer{Name: "Bottom"})).To(Succeed())
+			Expect(names()).To(Equal([]string{"Bottom", "Floor", "Below", "Above", "Top"}))
+			Expect(m.InsertLayer(6, Layer{})).ToNot(Succeed())
+
+			Expect(m.MoveLayer(0, 3)).To(Succeed())
+			Expect(names()).To(Equal([]string{"Floor", "Below", "Above", "Bottom", "Top"}))
+			Expect(m.MoveLayer(4, 0)).To(Succeed())
+			Expect(names()).To(Equal([]string{"Top", "Floor", "Below", "Above", "Bottom"}))
+			Expect(m.MoveLayer(0, 5)).ToNot(Succeed())
+
+			Expect(m.RemoveLayer(4)).To(Succeed())
+			Expect(m.RemoveLayer(0)).To(Succeed())
+			Expect(names()).To(Equal([]string{"Floor", "Below", "Above"}))
+			Expect(m.RemoveLayer(3)).ToNot(Succeed())
+		})
+
+		It("finds, moves, inserts and removes layers in groups", func() {
+			m.Groups = []Group{{Name: "Roof", Layers: []Layer{{Name: "Tiles"}, {Name: "Chimney"}}}}
+			allNames := func() []string {
+				var result []string
+				for _, l := range m.AllLayers() {
+					result = append(result, l.Name)
+				}
+
+				return result
+			}
+
+			Expect(allNames()).To(Equal([]string{"Floor", "Below", "Above", "Tiles", "Chimney"}))
+			Expect(m.GetLayerByName("Chimney")).To(BeIdenticalTo(&m.Groups[0].Layers[1]))
+
+			Expect(m.MoveLayer(4, 0)).To(Succeed())
+			Expect(allNames()).To(Equal([]string{"Chimney", "Floor", "Below", "Above", "Tiles"}))
+			Expect(names()).To(Equal([]string{"Chimney", "Floor", "Below", "Above"}))
+
+			Expect(m.MoveLayer(1, 4)).To(Succeed())
+			Expect(allNames()).To(Equal([]string{"Chimney", "Below", "Above", "Tiles", "Floor"}))
+			Expect(m.Groups[0].Layers).To(HaveLen(2))
+
+			Expect(m.InsertLayer(3, Layer{Name: "Gutter"})).To(Succeed())
+			Expect(m.InsertLayer(6, Layer{Name: "Antenna"})).To(Succeed())
+			Expect(m.Groups[0].Layers[0].Name).To(Equal("Gutter"))
+			Expect(m.Groups[0].Layers[3].Name).To(Equal("Antenna"))
+
+			Expect(m.RemoveLayer(4)).To(Succeed())
+			Expect(allNames()).To(Equal([]string{"Chimney", "Below", "Above", "Gutter", "Floor", "Antenna"}))
+		})
+
+		It("adds tilesets after all used gids", func() {
+			Expect(m.NextFirstGID()).To(Equal(GID(1001)))
+			tileset := Tileset{Name: "props", TileWidth: 32, TileHeight: 32, Image: Image{Source: "chipset.png", Width: 320, Height: 320}}
+			Expect(m.AddTileset(tileset)).To(Equal(GID(1001)))
+			Expect(m.AddTileset(tileset)).To(Equal(GID(1101)))
+
+			found, err := m.GetTilesetForGID(1100)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeIdenticalTo(&m.Tilesets[2]))
+			found, err = m.GetTilesetForGID(1101)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeIdenticalTo(&m.Tilesets[3]))
+			Expect(new(Map).NextFirstGID()).To(Equal(GID(1)))
+		})
+
+		It("encodes edited maps", func() {
+			layer := m.AddLayer("Top")
+			layer.Fill(1001, 0)
+			m.AddTileset(Tileset{Name: "props", TileWidth: 32, TileHeight: 32, Image: Image{Source: "chipset.png", Width: 320, Height: 320}})
+			Expect(m.Layers[0].SetTile(0, 0, 42, GIDDiagonalFlip)).To(Succeed())
+
+			var buffer bytes.Buffer
+			Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+			decoded, err := NewMap(&buffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.Layers).To(HaveLen(4))
+			Expect(decoded.Layers[0].TileAt(0, 0)).To(Equal(DataTile{GID: 42, DiagonalFlip: true}))
+			Expect(decoded.GetTileForGID(1001)).To(BeNil())
+			Expect(gids(decoded.Layers[3])).To(Equal(gids(*layer)))
+		})
+	})
+
+	Context("Test resizing maps", func() {
+		var m Map
+
+		BeforeEach(func() {
+			m = Map{Width: 2, Height: 2, TileWidth: 16, TileHeight: 8}
+			layer := m.AddLayer("Ground")
+			for i := range layer.Data.DataTiles {
+				layer.Data.DataTiles[i].GID = GID(i + 1)
+			}
+
+			m.ObjectGroups = []ObjectGroup{{Objects: []Object{{X: 4, Y: 2}}}}
+		})
+
+		It("keeps tiles at the anchor", func() {
+			expected := map[Anchor][]GID{
+				AnchorTopLeft:     {1, 2, 0, 0, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+				AnchorTop:         {0, 1, 2, 0, 0, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+				AnchorCenter:      {0, 0, 0, 0, 0, 1, 2, 0, 0, 3, 4, 0, 0, 0, 0, 0},
+				AnchorBottomRight: {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 0, 0, 3, 4},
+			}
+
+			for anchor, gidsAt := range expected {
+				resized := m
+				resized.Layers = []Layer{m.Layers[0]}
+				resized.ObjectGroups = []ObjectGroup{{Objects: []Object{m.ObjectGroups[0].Objects[0]}}}
+				Expect(resized.Resize(4, 4, anchor)).To(Succeed())
+				Expect(resized.Width).To(Equal(4))
+				Expect(resized.Layers[0].Width).To(Equal(4))
+				Expect(resized.Layers[0].Height).To(Equal(4))
+				Expect(gids(resized.Layers[0])).To(Equal(gidsAt))
+			}
+		})
+
+		It("moves objects", func() {
+			Expect(m.Resize(4, 4, AnchorBottomRight)).To(Succeed())
+			Expect(m.ObjectGroups[0].Objects[0].X).To(Equal(36.0))
+			Expect(m.ObjectGroups[0].Objects[0].Y).To(Equal(18.0))
+		})
+
+		It("crops maps", func() {
+			Expect(m.Resize(1, 1, AnchorBottomRight)).To(Succeed())
+			Expect(gids(m.Layers[0])).To(Equal([]GID{4}))
+			Expect(m.ObjectGroups[0].Objects[0].X).To(Equal(-12.0))
+
+			Expect(m.Resize(0, 1, AnchorTopLeft)).ToNot(Succeed())
+		})
+	})
+})
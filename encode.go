@@ -0,0 +1,316 @@
+package tmx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+//EncodeOptions configure how a map is written
+type EncodeOptions struct {
+	//Encoding of the tile layer data, either "base64", "csv" or "xml".
+	//Defaults to "base64" for TMX and "csv" for JSON files
+	Encoding string
+	//Compression of base64 encoded data, either "", "gzip", "zlib" or "zstd"
+	Compression string
+	//Project stores the names of enums as int again if set,
+	//it is needed for maps loaded with a project
+	Project *Project
+}
+
+//Encode writes the map as Tiled compatible TMX file,
+//layer data is encoded from DataTiles. External tilesets
+//are only referenced and not written.
+func (m *Map) Encode(w io.Writer, opts EncodeOptions) error {
+	encoding := opts.Encoding
+	if encoding == "" {
+		encoding = "base64"
+	}
+
+	switch encoding {
+	case "base64":
+	case "csv", "xml":
+		if opts.Compression != "" {
+			return fmt.Errorf("%s encoded data can't be compressed", encoding)
+		}
+	default:
+		return fmt.Errorf("unsupported encoding %s", encoding)
+	}
+
+	target := *m
+	if opts.Project != nil {
+		target = opts.Project.storeMap(target)
+	}
+
+	root := target.root().withEncoding(encoding, opts.Compression)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", " ")
+	ordered := orderedMap{Map: target, Layers: root.orderedChildren()}
+	if err := encoder.EncodeElement(ordered, xml.StartElement{Name: xml.Name{Local: "map"}}); err != nil {
+		return err
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+//orderedMap writes the layers of a map in the order they are drawn,
+//its fields hide the separate layer lists of Map
+type orderedMap struct {
+	Map
+	Layers       []orderedChild `xml:"layer"`
+	ObjectGroups []ObjectGroup  `xml:"objectgroup"`
+	Groups       []Group        `xml:"group"`
+}
+
+//orderedGroup writes the layers of a group in the order they are drawn
+type orderedGroup struct {
+	Group
+	Layers       []orderedChild `xml:"layer"`
+	ObjectGroups []ObjectGroup  `xml:"objectgroup"`
+	Groups       []Group        `xml:"group"`
+}
+
+//orderedChild writes a child of a map or group as element of its kind
+type orderedChild groupChild
+
+//orderedChildren returns the children of g in the order they are drawn
+func (g Group) orderedChildren() []orderedChild {
+	children := g.children()
+	ordered := make([]orderedChild, len(children))
+	for i, child := range children {
+		ordered[i] = orderedChild(child)
+	}
+
+	return ordered
+}
+
+//MarshalXML writes the layer, object group or group of c
+func (c orderedChild) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	switch {
+	case c.layer != nil:
+		return e.EncodeElement(c.layer, xml.StartElement{Name: xml.Name{Local: "layer"}})
+	case c.objectGroup != nil:
+		return e.EncodeElement(c.objectGroup, xml.StartElement{Name: xml.Name{Local: "objectgroup"}})
+	}
+
+	ordered := orderedGroup{Group: *c.group, Layers: c.group.orderedChildren()}
+	return e.EncodeElement(ordered, xml.StartElement{Name: xml.Name{Local: "group"}})
+}
+
+//withEncoding returns a copy of g whose tile layers
+//and groups are written with the given encoding
+func (g Group) withEncoding(encoding, compression string) Group {
+	layers := make([]Layer, len(g.Layers))
+	for i, l := range g.Layers {
+		l.Data.Encoding = encoding
+		if encoding == "xml" {
+			l.Data.Encoding = ""
+		}
+
+		l.Data.Compression = compression
+		layers[i] = l
+	}
+
+	groups := make([]Group, len(g.Groups))
+	for i, group := range g.Groups {
+		groups[i] = group.withEncoding(encoding, compression)
+	}
+
+	g.Layers = layers
+	g.Groups = groups
+	return g
+}
+
+//MarshalXML writes the tile data with the
+//given encoding and compression
+func (d Data) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if d.Encoding != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "encoding"}, Value: d.Encoding})
+	}
+
+	if d.Compression != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "compression"}, Value: d.Compression})
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch d.Encoding {
+	case "":
+		for _, tile := range d.DataTiles {
+			element := xml.StartElement{
+				Name: xml.Name{Local: "tile"},
+				Attr: []xml.Attr{{Name: xml.Name{Local: "gid"}, Value: strconv.FormatUint(uint64(tile.rawGID()), 10)}},
+			}
+
+			if err := e.EncodeElement("", element); err != nil {
+				return err
+			}
+		}
+	case "csv":
+		if err := e.EncodeToken(xml.CharData(d.encodeCSV())); err != nil {
+			return err
+		}
+	case "base64":
+		data, err := d.encodeBase64()
+		if err != nil {
+			return err
+		}
+
+		if err := e.EncodeToken(xml.CharData("\n" + data + "\n")); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported encoding %s", d.Encoding)
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+//encodeCSV returns all gids comma separated
+func (d Data) encodeCSV() string {
+	gids := make([]string, len(d.DataTiles))
+	for i, tile := range d.DataTiles {
+		gids[i] = strconv.FormatUint(uint64(tile.rawGID()), 10)
+	}
+
+	return "\n" + strings.Join(gids, ",") + "\n"
+}
+
+//encodeBase64 returns all gids compressed and base64 encoded
+func (d Data) encodeBase64() (string, error) {
+	raw := make([]byte, 0, len(d.DataTiles)*4)
+	for _, tile := range d.DataTiles {
+		gid := tile.rawGID()
+		raw = append(raw, byte(gid), byte(gid>>8), byte(gid>>16), byte(gid>>24))
+	}
+
+	compressed, err := compress(raw, d.Compression)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(compressed), nil
+}
+
+// compress data with the given `compression` standard
+func compress(data []byte, compression string) ([]byte, error) {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch compression {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "zlib":
+		writer = zlib.NewWriter(&buffer)
+	case "zstd":
+		var err error
+		writer, err = zstd.NewWriter(&buffer)
+		if err != nil {
+			return nil, err
+		}
+	case "":
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", compression)
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+//tilesetXML is used to encode tilesets with the default encoder
+type tilesetXML Tileset
+
+//MarshalXML only writes a reference to external tilesets
+func (t Tileset) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if t.Source == "" {
+		return e.EncodeElement(tilesetXML(t), start)
+	}
+
+	start.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "firstgid"}, Value: strconv.FormatUint(uint64(t.FirstGID), 10)},
+		{Name: xml.Name{Local: "source"}, Value: t.Source},
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+//imageXML is used to encode images with the default encoder
+type imageXML Image
+
+//MarshalXML does not write images without source
+func (i Image) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if i.Source == "" {
+		return nil
+	}
+
+	return e.EncodeElement(imageXML(i), start)
+}
+
+//MarshalXML writes the flags as 0 or 1 like Tiled does
+func (t Transformations) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	flags := []struct {
+		name  string
+		value bool
+	}{
+		{"hflip", t.HFlip},
+		{"vflip", t.VFlip},
+		{"rotate", t.Rotate},
+		{"preferuntransformed", t.PreferUntransformed},
+	}
+
+	start.Attr = nil
+	for _, flag := range flags {
+		value := "0"
+		if flag.value {
+			value = "1"
+		}
+
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: flag.name}, Value: value})
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+//MarshalXMLAttr only writes invisible values
+func (v *visibleValue) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if v == nil || v.value {
+		return xml.Attr{}, nil
+	}
+
+	return xml.Attr{Name: name, Value: "0"}, nil
+}
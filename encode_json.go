@@ -0,0 +1,417 @@
+package tmx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//EncodeJSON writes the map in the Tiled JSON format. Tile layer data is
+//written as array of gids for the "csv" encoding, which is the default,
+//or as base64 string with the given compression. External tilesets
+//and templates are only referenced and not written.
+func (m *Map) EncodeJSON(w io.Writer, opts EncodeOptions) error {
+	encoding := opts.Encoding
+	if encoding == "" {
+		encoding = "csv"
+	}
+
+	switch encoding {
+	case "base64":
+	case "csv":
+		if opts.Compression != "" {
+			return fmt.Errorf("%s encoded data can't be compressed", encoding)
+		}
+	default:
+		return fmt.Errorf("unsupported encoding %s", encoding)
+	}
+
+	if opts.Project != nil {
+		stored := opts.Project.storeMap(*m)
+		m = &stored
+	}
+
+	encoded := jsonMap{
+		Type:            "map",
+		Class:           m.Class,
+		Orientation:     m.Orientation,
+		RenderOrder:     m.RenderOrder,
+		StaggerAxis:     m.StaggerAxis,
+		StaggerIndex:    m.StaggerIndex,
+		HexSideLength:   m.HexSideLength,
+		Width:           m.Width,
+		Height:          m.Height,
+		TileWidth:       m.TileWidth,
+		TileHeight:      m.TileHeight,
+		BackgroundColor: string(m.BackgroundColor),
+		NextObjectID:    m.NextObjectID,
+		Properties:      m.Properties,
+		Tilesets:        m.Tilesets,
+	}
+
+	if m.Version != "" {
+		version, err := json.Marshal(m.Version)
+		if err != nil {
+			return err
+		}
+
+		encoded.Version = version
+	}
+
+	if encoded.Tilesets == nil {
+		encoded.Tilesets = []Tileset{}
+	}
+
+	root, err := m.root().toJSON(encoding, opts.Compression)
+	if err != nil {
+		return err
+	}
+
+	encoded.Layers = root.Layers
+	if encoded.Layers == nil {
+		encoded.Layers = []jsonLayer{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", " ")
+	return encoder.Encode(encoded)
+}
+
+//toJSON converts l to a JSON tile layer with the given encoding
+func (l Layer) toJSON(encoding, compression string) (jsonLayer, error) {
+	visible := l.IsVisible()
+	layer := jsonLayer{
+		Type:       "tilelayer",
+		Name:       l.Name,
+		Class:      l.Class,
+		Width:      l.Width,
+		Height:     l.Height,
+		Opacity:    opacityJSON(l.Opacity),
+		Visible:    &visible,
+		Properties: l.Properties,
+	}
+
+	var data interface{}
+	if encoding == "base64" {
+		layer.Encoding = encoding
+		layer.Compression = compression
+
+		encoded, err := Data{DataTiles: l.Data.DataTiles, Compression: compression}.encodeBase64()
+		if err != nil {
+			return layer, err
+		}
+
+		data = encoded
+	} else {
+		gids := make([]GID, len(l.Data.DataTiles))
+		for i, tile := range l.Data.DataTiles {
+			gids[i] = tile.rawGID()
+		}
+
+		data = gids
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return layer, err
+	}
+
+	layer.Data = raw
+
+	return layer, nil
+}
+
+//toJSON converts g to a JSON group, its tile
+//layers are written with the given encoding
+func (g Group) toJSON(encoding, compression string) (jsonLayer, error) {
+	visible := g.IsVisible()
+	group := jsonLayer{
+		Type:       "group",
+		Name:       g.Name,
+		Class:      g.Class,
+		Opacity:    opacityJSON(g.Opacity),
+		Visible:    &visible,
+		OffsetX:    g.OffsetX,
+		OffsetY:    g.OffsetY,
+		Properties: g.Properties,
+	}
+
+	for _, child := range g.children() {
+		var layer jsonLayer
+		var err error
+		switch {
+		case child.layer != nil:
+			layer, err = child.layer.toJSON(encoding, compression)
+		case child.objectGroup != nil:
+			layer, err = child.objectGroup.toJSON()
+		default:
+			layer, err = child.group.toJSON(encoding, compression)
+		}
+
+		if err != nil {
+			return group, err
+		}
+
+		group.Layers = append(group.Layers, layer)
+	}
+
+	return group, nil
+}
+
+//toJSON converts g to a JSON object group
+func (g ObjectGroup) toJSON() (jsonLayer, error) {
+	visible := g.IsVisible()
+	group := jsonLayer{
+		Type:       "objectgroup",
+		Name:       g.Name,
+		Class:      g.Class,
+		Color:      g.Color,
+		Opacity:    opacityJSON(g.Opacity),
+		Visible:    &visible,
+		Properties: g.Properties,
+	}
+
+	for _, o := range g.Objects {
+		object, err := o.toJSON()
+		if err != nil {
+			return group, err
+		}
+
+		group.Objects = append(group.Objects, object)
+	}
+
+	return group, nil
+}
+
+//toJSON converts o to a JSON object
+func (o Object) toJSON() (jsonObject, error) {
+	visible := o.IsVisible()
+	object := jsonObject{
+		ID:         o.ID,
+		Name:       o.Name,
+		Type:       o.Type,
+		X:          o.X,
+		Y:          o.Y,
+		Width:      o.Width,
+		Height:     o.Height,
+		Rotation:   o.Rotation,
+		GID:        o.GID,
+		Template:   o.Template,
+		Visible:    &visible,
+		Ellipse:    o.Ellipse != nil,
+		Point:      o.Point != nil,
+		Properties: o.Properties,
+	}
+
+	if len(o.Polygons) > 0 {
+		points, err := o.Polygons[0].Vertices()
+		if err != nil {
+			return object, err
+		}
+
+		object.Polygon = points
+	}
+
+	if len(o.PolyLines) > 0 {
+		points, err := o.PolyLines[0].Vertices()
+		if err != nil {
+			return object, err
+		}
+
+		object.PolyLine = points
+	}
+
+	return object, nil
+}
+
+//opacityJSON converts an opacity, unset opacities are fully opaque
+func opacityJSON(opacity float32) *float32 {
+	if opacity == 0 {
+		opacity = 1
+	}
+
+	return &opacity
+}
+
+//MarshalJSON writes the tileset in the Tiled JSON format,
+//external tilesets are only referenced
+func (t Tileset) MarshalJSON() ([]byte, error) {
+	if t.Source != "" {
+		return json.Marshal(jsonTileset{FirstGID: t.FirstGID, Source: t.Source})
+	}
+
+	encoded := jsonTileset{
+		FirstGID:        t.FirstGID,
+		Name:            t.Name,
+		Class:           t.Class,
+		TileWidth:       t.TileWidth,
+		TileHeight:      t.TileHeight,
+		Spacing:         t.Spacing,
+		Margin:          t.Margin,
+		TileOffset:      t.TileOffset,
+		Transformations: t.Transformations,
+		Image:           t.Image.Source,
+		ImageWidth:      t.Image.Width,
+		ImageHeight:     t.Image.Height,
+		TileCount:       t.GetNumTiles(),
+		Columns:         t.GetNumTilesX(),
+		Properties:      t.Properties,
+		Terrains:        t.TerrainTypes,
+		WangSets:        t.WangSets,
+	}
+
+	if t.Image.Trans != "" {
+		encoded.TransparentColor = "#" + t.Image.Trans
+	}
+
+	for _, tile := range t.Tiles {
+		encodedTile := jsonTile{
+			ID:          tile.ID,
+			Type:        tile.Type,
+			Class:       tile.Class,
+			Probability: tile.Probability,
+			Properties:  tile.Properties,
+			Image:       tile.Image.Source,
+			ImageWidth:  tile.Image.Width,
+			ImageHeight: tile.Image.Height,
+		}
+
+		//the JSON format only supports one transparent color per tileset
+		if encoded.TransparentColor == "" && tile.Image.Trans != "" {
+			encoded.TransparentColor = "#" + tile.Image.Trans
+		}
+
+		if tile.Terrain != "" {
+			terrain, err := terrainIndices(tile.Terrain)
+			if err != nil {
+				return nil, err
+			}
+
+			encodedTile.Terrain = terrain
+		}
+
+		if tile.ObjectGroup != nil {
+			group, err := tile.ObjectGroup.toJSON()
+			if err != nil {
+				return nil, err
+			}
+
+			encodedTile.ObjectGroup = &group
+		}
+
+		if tile.Animation != nil {
+			for _, frame := range tile.Animation.Frames {
+				encodedTile.Animation = append(encodedTile.Animation, *frame)
+			}
+		}
+
+		encoded.Tiles = append(encoded.Tiles, encodedTile)
+	}
+
+	return json.Marshal(encoded)
+}
+
+//terrainIndices converts the terrain attribute of a tile
+//to JSON terrain indices, empty values are written as -1
+func terrainIndices(terrain string) ([]int, error) {
+	corners := strings.Split(terrain, ",")
+	indices := make([]int, len(corners))
+	for i, corner := range corners {
+		if corner == "" {
+			indices[i] = -1
+			continue
+		}
+
+		index, err := strconv.Atoi(corner)
+		if err != nil {
+			return nil, err
+		}
+
+		indices[i] = index
+	}
+
+	return indices, nil
+}
+
+//MarshalJSON writes the properties in the Tiled JSON format
+func (p Properties) MarshalJSON() ([]byte, error) {
+	encoded := make([]jsonProperty, len(p))
+	for i, property := range p {
+		value, err := property.marshalValue()
+		if err != nil {
+			return nil, err
+		}
+
+		encoded[i] = jsonProperty{
+			Name:         property.Name,
+			Type:         property.Type,
+			PropertyType: property.PropertyType,
+			Value:        value,
+		}
+
+		if encoded[i].Type == "" {
+			encoded[i].Type = "string"
+		}
+	}
+
+	return json.Marshal(encoded)
+}
+
+//marshalValue returns the JSON value of p, class properties are written
+//as object of their members. An error is returned if the value of an int,
+//float, object or bool property is invalid.
+func (p Property) marshalValue() (json.RawMessage, error) {
+	switch p.Type {
+	case "class":
+		var buffer bytes.Buffer
+		buffer.WriteByte('{')
+		for i, member := range p.Properties {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			name, err := json.Marshal(member.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := member.marshalValue()
+			if err != nil {
+				return nil, err
+			}
+
+			buffer.Write(name)
+			buffer.WriteByte(':')
+			buffer.Write(value)
+		}
+		buffer.WriteByte('}')
+
+		return buffer.Bytes(), nil
+	case "int", "object":
+		value, err := strconv.Atoi(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value of property %s: %w", p.Type, p.Name, err)
+		}
+
+		return json.RawMessage(strconv.Itoa(value)), nil
+	case "float":
+		value, err := strconv.ParseFloat(p.Value, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("invalid float value of property %s: %q", p.Name, p.Value)
+		}
+
+		return json.RawMessage(strconv.FormatFloat(value, 'g', -1, 64)), nil
+	case "bool":
+		if p.Value != "true" && p.Value != "false" {
+			return nil, fmt.Errorf("invalid bool value of property %s: %q", p.Name, p.Value)
+		}
+
+		return json.RawMessage(p.Value), nil
+	}
+
+	return json.Marshal(p.Value)
+}
@@ -0,0 +1,172 @@
+package tmx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing/fstest"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test JSON encoding", func() {
+
+	roundTrip := func(m *Map, opts EncodeOptions) *Map {
+		var buffer bytes.Buffer
+		Expect(m.EncodeJSON(&buffer, opts)).To(Succeed())
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		return decoded
+	}
+
+	files := []string{
+		"testfiles/simple_example.tmx",
+		"testfiles/simple_example_zlib.tmx",
+		"testfiles/uncompressed_not_square.tmx",
+		"testfiles/animated_example_zlib.tmx",
+		"testfiles/transparent_example.tmx",
+		"testfiles/hexagonal.tmx",
+		"testfiles/simple_example.tmj",
+	}
+
+	for name, opts := range map[string]EncodeOptions{
+		"default options":        {},
+		"arrays":                 {Encoding: "csv"},
+		"base64 uncompressed":    {Encoding: "base64"},
+		"base64 gzip compressed": {Encoding: "base64", Compression: "gzip"},
+		"base64 zlib compressed": {Encoding: "base64", Compression: "zlib"},
+		"base64 zstd compressed": {Encoding: "base64", Compression: "zstd"},
+	} {
+		opts := opts
+		It("encodes and decodes maps with "+name, func() {
+			for _, filename := range files {
+				m := mustLoadTestMap(filename)
+				expectEqualMaps(roundTrip(m, opts), m)
+			}
+		})
+	}
+
+	It("writes Tiled compatible JSON", func() {
+		m := mustLoadTestMap("testfiles/animated_example_zlib.tmx")
+		m.Layers[1].Data.DataTiles[0].HorizontalFlip = true
+
+		var buffer bytes.Buffer
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+
+		var encoded struct {
+			Type     string
+			Version  string
+			Tilesets []struct {
+				FirstGID  int `json:"firstgid"`
+				Image     string
+				TileCount int
+				Columns   int
+				Tiles     []struct {
+					ID        int
+					Animation []struct {
+						TileID   int
+						Duration int
+					}
+				}
+			}
+			Layers []struct {
+				Type    string
+				Name    string
+				Opacity float64
+				Visible bool
+				Data    []uint32
+			}
+		}
+		Expect(json.Unmarshal(buffer.Bytes(), &encoded)).To(Succeed())
+
+		Expect(encoded.Type).To(Equal("map"))
+		Expect(encoded.Version).To(Equal("1.0"))
+		Expect(encoded.Tilesets).To(HaveLen(1))
+		Expect(encoded.Tilesets[0].FirstGID).To(Equal(1))
+		Expect(encoded.Tilesets[0].Image).To(Equal("chipset.png"))
+		Expect(encoded.Tilesets[0].TileCount).To(Equal(500))
+		Expect(encoded.Tilesets[0].Columns).To(Equal(10))
+		Expect(encoded.Tilesets[0].Tiles[0].ID).To(Equal(32))
+		Expect(encoded.Tilesets[0].Tiles[0].Animation).To(HaveLen(4))
+		Expect(encoded.Tilesets[0].Tiles[0].Animation[0].TileID).To(Equal(3))
+		Expect(encoded.Tilesets[0].Tiles[0].Animation[0].Duration).To(Equal(100))
+
+		Expect(encoded.Layers).To(HaveLen(4))
+		Expect(encoded.Layers[1].Type).To(Equal("tilelayer"))
+		Expect(encoded.Layers[1].Name).To(Equal("Invisible"))
+		Expect(encoded.Layers[1].Opacity).To(Equal(1.0))
+		Expect(encoded.Layers[1].Visible).To(BeFalse())
+		Expect(encoded.Layers[1].Data).To(HaveLen(24 * 24))
+		Expect(encoded.Layers[1].Data[0] & GIDHorizontalFlip).ToNot(BeZero())
+	})
+
+	It("writes references to external files", func() {
+		fsys := fstest.MapFS{}
+		for _, name := range []string{
+			"external/external_example.tmx",
+			"tilesets/chipset.tsx",
+			"templates/chest.tx",
+		} {
+			data, err := ioutil.ReadFile("testfiles/" + name)
+			Expect(err).ToNot(HaveOccurred())
+			fsys[name] = &fstest.MapFile{Data: data}
+		}
+
+		m, err := LoadMapFS(fsys, "external/external_example.tmx")
+		Expect(err).ToNot(HaveOccurred())
+
+		var buffer bytes.Buffer
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`"source": "../tilesets/chipset.tsx"`))
+		Expect(buffer.String()).To(ContainSubstring(`"template": "../templates/chest.tx"`))
+		Expect(buffer.String()).To(ContainSubstring(`"type": "objectgroup"`))
+		Expect(buffer.String()).ToNot(ContainSubstring(`"image"`))
+
+		fsys["external/encoded.tmj"] = &fstest.MapFile{Data: buffer.Bytes()}
+		decoded, err := LoadMapFS(fsys, "external/encoded.tmj")
+		Expect(err).ToNot(HaveOccurred())
+		expectEqualMaps(decoded, m)
+	})
+
+	It("fails for unsupported options", func() {
+		m := mustLoadTestMap("testfiles/simple_example.tmx")
+		Expect(m.EncodeJSON(ioutil.Discard, EncodeOptions{Encoding: "xml"})).ToNot(Succeed())
+		Expect(m.EncodeJSON(ioutil.Discard, EncodeOptions{Encoding: "base64", Compression: "lzma"})).ToNot(Succeed())
+		Expect(m.EncodeJSON(ioutil.Discard, EncodeOptions{Compression: "zlib"})).ToNot(Succeed())
+	})
+
+	It("fails for invalid property values", func() {
+		for _, p := range []Property{
+			{Name: "speed", Type: "float", Value: "NaN"},
+			{Name: "speed", Type: "float", Value: "-Inf"},
+			{Name: "count", Type: "int", Value: "1_000"},
+			{Name: "count", Type: "int", Value: "1.5"},
+			{Name: "target", Type: "object", Value: ""},
+			{Name: "solid", Type: "bool", Value: "yes"},
+		} {
+			m := mustLoadTestMap("testfiles/simple_example.tmx")
+			m.Properties = Properties{p}
+			Expect(m.EncodeJSON(ioutil.Discard, EncodeOptions{})).ToNot(Succeed(), p.Value)
+		}
+	})
+
+	It("normalizes numeric property values", func() {
+		m := mustLoadTestMap("testfiles/simple_example.tmx")
+		m.Properties = Properties{
+			{Name: "count", Type: "int", Value: "+3"},
+			{Name: "speed", Type: "float", Value: "1.50"},
+			{Name: "scale", Type: "float", Value: "0x1p-2"},
+		}
+		var buffer bytes.Buffer
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Properties).To(Equal(Properties{
+			{Name: "count", Type: "int", Value: "3"},
+			{Name: "speed", Type: "float", Value: "1.5"},
+			{Name: "scale", Type: "float", Value: "0.25"},
+		}))
+	})
+})
@@ -0,0 +1,97 @@
+package tmx_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing/fstest"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test TMX encoding", func() {
+
+	roundTrip := func(m *Map, opts EncodeOptions) *Map {
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, opts)).To(Succeed())
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		return decoded
+	}
+
+	files := []string{
+		"testfiles/simple_example.tmx",
+		"testfiles/simple_example_zlib.tmx",
+		"testfiles/uncompressed_not_square.tmx",
+		"testfiles/animated_example_zlib.tmx",
+		"testfiles/transparent_example.tmx",
+		"testfiles/hexagonal.tmx",
+	}
+
+	for name, opts := range map[string]EncodeOptions{
+		"default options":        {},
+		"base64 uncompressed":    {Encoding: "base64"},
+		"base64 gzip compressed": {Encoding: "base64", Compression: "gzip"},
+		"base64 zlib compressed": {Encoding: "base64", Compression: "zlib"},
+		"base64 zstd compressed": {Encoding: "base64", Compression: "zstd"},
+		"csv":                    {Encoding: "csv"},
+		"xml":                    {Encoding: "xml"},
+	} {
+		opts := opts
+		It("encodes and decodes maps with "+name, func() {
+			for _, filename := range files {
+				m := mustLoadTestMap(filename)
+				expectEqualMaps(roundTrip(m, opts), m)
+			}
+		})
+	}
+
+	It("keeps flipped tiles", func() {
+		m := mustLoadTestMap("testfiles/simple_example.tmx")
+		m.Layers[0].Data.DataTiles[0].GID = 3
+		m.Layers[0].Data.DataTiles[0].HorizontalFlip = true
+		m.Layers[0].Data.DataTiles[1].GID = 4
+		m.Layers[0].Data.DataTiles[1].DiagonalFlip = true
+
+		for _, opts := range []EncodeOptions{{Encoding: "xml"}, {Encoding: "csv"}, {Compression: "zlib"}} {
+			decoded := roundTrip(m, opts)
+			Expect(decoded.Layers[0].Data.DataTiles[0]).To(Equal(m.Layers[0].Data.DataTiles[0]))
+			Expect(decoded.Layers[0].Data.DataTiles[1]).To(Equal(m.Layers[0].Data.DataTiles[1]))
+		}
+	})
+
+	It("writes references to external files", func() {
+		fsys := fstest.MapFS{}
+		for _, name := range []string{
+			"external/external_example.tmx",
+			"tilesets/chipset.tsx",
+			"templates/chest.tx",
+		} {
+			data, err := ioutil.ReadFile("testfiles/" + name)
+			Expect(err).ToNot(HaveOccurred())
+			fsys[name] = &fstest.MapFile{Data: data}
+		}
+
+		m, err := LoadMapFS(fsys, "external/external_example.tmx")
+		Expect(err).ToNot(HaveOccurred())
+
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{Compression: "gzip"})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`<tileset firstgid="1" source="../tilesets/chipset.tsx"></tileset>`))
+		Expect(buffer.String()).To(ContainSubstring(`template="../templates/chest.tx"`))
+		Expect(buffer.String()).ToNot(ContainSubstring("<image"))
+
+		fsys["external/encoded.tmx"] = &fstest.MapFile{Data: buffer.Bytes()}
+		decoded, err := LoadMapFS(fsys, "external/encoded.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		expectEqualMaps(decoded, m)
+	})
+
+	It("fails for unsupported options", func() {
+		m := mustLoadTestMap("testfiles/simple_example.tmx")
+		Expect(m.Encode(ioutil.Discard, EncodeOptions{Encoding: "json"})).ToNot(Succeed())
+		Expect(m.Encode(ioutil.Discard, EncodeOptions{Compression: "lzma"})).ToNot(Succeed())
+		Expect(m.Encode(ioutil.Discard, EncodeOptions{Encoding: "csv", Compression: "zlib"})).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,201 @@
+package tmx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//externalFiles opens files referenced by a map,
+//names are resolved slash separated paths
+type externalFiles interface {
+	open(name string) (io.ReadCloser, error)
+}
+
+//osFiles opens files from the filesystem
+type osFiles struct{}
+
+func (o osFiles) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+//fsFiles opens files from a fs.FS
+type fsFiles struct {
+	fsys fs.FS
+}
+
+func (f fsFiles) open(name string) (io.ReadCloser, error) {
+	return f.fsys.Open(fsPath(name))
+}
+
+//template is an object template stored in a .tx file
+type template struct {
+	Tileset *Tileset `xml:"tileset"`
+	Object  Object   `xml:"object"`
+}
+
+//loadExternals loads all tilesets and templates referenced by m
+func loadExternals(m *Map, files externalFiles) error {
+	for i, tileset := range m.Tilesets {
+		if tileset.Source == "" {
+			continue
+		}
+
+		loaded, err := loadTileset(files, m.resolver.Resolve(m.filename, tileset.Source))
+		if err != nil {
+			return err
+		}
+
+		loaded.FirstGID = tileset.FirstGID
+		loaded.Source = tileset.Source
+		m.Tilesets[i] = loaded
+	}
+
+	templates := map[string]*template{}
+	return m.forEachObjectGroup(func(g *ObjectGroup) error {
+		objects := g.Objects
+		for j := range objects {
+			if objects[j].Template == "" {
+				continue
+			}
+
+			name := m.resolver.Resolve(m.filename, objects[j].Template)
+			t, ok := templates[name]
+			if !ok {
+				var err error
+				t, err = loadTemplate(files, name)
+				if err != nil {
+					return err
+				}
+
+				templates[name] = t
+			}
+
+			if err := t.apply(&objects[j], name, *m); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+//loadTileset loads the external tileset stored at name
+func loadTileset(files externalFiles, name string) (Tileset, error) {
+	var tileset Tileset
+	if err := decodeExternal(files, name, &tileset); err != nil {
+		return tileset, err
+	}
+
+	tileset.filename = name
+
+	return tileset, nil
+}
+
+//loadTemplate loads the object template stored at name
+func loadTemplate(files externalFiles, name string) (*template, error) {
+	var t template
+	if err := decodeExternal(files, name, &t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+//decodeExternal decodes the TMX or JSON file stored at name into target
+func decodeExternal(files externalFiles, name string, target interface{}) error {
+	f, err := files.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	if isJSON(name, data) {
+		err = json.Unmarshal(data, target)
+	} else {
+		err = xml.Unmarshal(data, target)
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not decode %s: %w", name, err)
+	}
+
+	return nil
+}
+
+//apply sets all values of o that are not set to the
+//values of the template stored at name
+func (t template) apply(o *Object, name string, m Map) error {
+	if o.Name == "" {
+		o.Name = t.Object.Name
+	}
+
+	if o.Type == "" {
+		o.Type = t.Object.Type
+	}
+
+	if o.Width == 0 {
+		o.Width = t.Object.Width
+	}
+
+	if o.Height == 0 {
+		o.Height = t.Object.Height
+	}
+
+	if o.Visible == nil {
+		o.Visible = t.Object.Visible
+	}
+
+	if len(o.Polygons) == 0 {
+		o.Polygons = t.Object.Polygons
+	}
+
+	if len(o.PolyLines) == 0 {
+		o.PolyLines = t.Object.PolyLines
+	}
+
+	for _, p := range t.Object.Properties {
+		found := false
+		for _, own := range o.Properties {
+			if own.Name == p.Name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			o.Properties = append(o.Properties, p)
+		}
+	}
+
+	if o.GID != 0 || t.Object.GID == 0 {
+		return nil
+	}
+
+	if t.Tileset == nil {
+		return fmt.Errorf("template %s has a tile but no tileset", name)
+	}
+
+	source := m.resolver.Resolve(name, t.Tileset.Source)
+	for _, tileset := range m.Tilesets {
+		if tileset.Source != "" && tileset.filename == source {
+			gid := GID(t.Object.GID)
+			flips := gid & GIDFlips
+			o.GID = int((gid&^GIDFlips - t.Tileset.FirstGID + tileset.FirstGID) | flips)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("tileset %s of template %s is not part of the map", source, name)
+}
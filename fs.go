@@ -0,0 +1,57 @@
+package tmx
+
+import (
+	"image"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//FSLocator loads images from a fs.FS, e.g. an embed.FS
+//or a zip archive. It supports the same formats as FilesystemLocator.
+type FSLocator struct {
+	FS fs.FS
+}
+
+//LocateResource to implement ResourceLocator interface
+func (f FSLocator) LocateResource(filepath string) (image.Image, error) {
+	file, err := f.FS.Open(fsPath(filepath))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, _, err := image.Decode(file)
+	return data, err
+}
+
+//fsPath converts a path generated by the renderer to a valid fs.FS path
+func fsPath(name string) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+}
+
+//LoadMapFS loads the map stored at name in fsys, external tilesets
+//and templates are loaded relative to the map from fsys.
+//To render the map use a FSLocator with the same fsys.
+func LoadMapFS(fsys fs.FS, name string) (*Map, error) {
+	return LoadMapFSWithOptions(fsys, name, LoadOptions{})
+}
+
+//LoadMapFSWithOptions loads the map stored at name in fsys
+//like LoadMapFS with custom LoadOptions
+func LoadMapFSWithOptions(fsys fs.FS, name string, opts LoadOptions) (*Map, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeMap(data, name, fsFiles{fsys: fsys}, opts)
+}
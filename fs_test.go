@@ -0,0 +1,103 @@
+package tmx_test
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"testing/fstest"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test io/fs support", func() {
+	expectExternals := func(m *Map) {
+		Expect(m.Tilesets).To(HaveLen(1))
+		tileset := m.Tilesets[0]
+		Expect(tileset.FirstGID).To(Equal(GID(1)))
+		Expect(tileset.Source).To(Equal("../tilesets/chipset.tsx"))
+		Expect(tileset.Name).To(Equal("chipset"))
+		Expect(tileset.Image.Width).To(Equal(320))
+		Expect(tileset.GetTileByID(32)).ToNot(BeNil())
+
+		Expect(m.ObjectGroups).To(HaveLen(1))
+		objects := m.ObjectGroups[0].Objects
+		Expect(objects).To(HaveLen(2))
+
+		Expect(objects[0].Name).To(Equal("chest"))
+		Expect(objects[0].Type).To(Equal("container"))
+		Expect(objects[0].GID).To(Equal(5))
+		Expect(objects[0].Width).To(Equal(32.0))
+		Expect(objects[0].X).To(Equal(64.0))
+		Expect(objects[0].Properties).To(ConsistOf(
+			Property{Name: "content", Value: "gold"},
+			Property{Name: "locked", Type: "bool", Value: "false"},
+		))
+
+		Expect(objects[1].Name).To(Equal("big chest"))
+		Expect(objects[1].Width).To(Equal(64.0))
+		Expect(objects[1].Properties).To(ConsistOf(
+			Property{Name: "content", Value: "diamonds"},
+			Property{Name: "locked", Type: "bool", Value: "false"},
+		))
+	}
+
+	expectRendering := func(m *Map, locator ResourceLocator) {
+		c := NewImageCanvasFromMap(*m)
+		renderer := NewRendererWithResourceLocator(*m, c, locator)
+		Expect(renderer.Render(0)).To(Succeed())
+		expectSimpleExampleImage(c.Image())
+	}
+
+	It("loads maps with external files from a directory", func() {
+		fsys := os.DirFS("testfiles")
+		m, err := LoadMapFS(fsys, "external/external_example.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		expectExternals(m)
+		expectRendering(m, FSLocator{FS: fsys})
+	})
+
+	It("loads maps from memory", func() {
+		fsys := fstest.MapFS{}
+		for _, name := range []string{
+			"external/external_example.tmx",
+			"tilesets/chipset.tsx",
+			"templates/chest.tx",
+			"chipset.png",
+		} {
+			data, err := ioutil.ReadFile("testfiles/" + name)
+			Expect(err).ToNot(HaveOccurred())
+			fsys["assets/"+name] = &fstest.MapFile{Data: data}
+		}
+
+		m, err := LoadMapFS(fsys, "assets/external/external_example.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		expectExternals(m)
+		expectRendering(m, FSLocator{FS: fsys})
+	})
+
+	It("loads external files of maps opened from disk", func() {
+		f, err := os.Open("testfiles/external/external_example.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		m, err := NewMap(f)
+		Expect(err).ToNot(HaveOccurred())
+		expectExternals(m)
+		expectRendering(m, FilesystemLocator{})
+	})
+
+	It("fails for missing external files", func() {
+		fsys := fstest.MapFS{"map.tmx": &fstest.MapFile{Data: []byte(
+			`<map width="1" height="1"><tileset firstgid="1" source="missing.tsx"/></map>`,
+		)}}
+		_, err := LoadMapFS(fsys, "map.tmx")
+		Expect(errors.Is(err, fs.ErrNotExist)).To(BeTrue())
+	})
+
+	It("fails for missing images", func() {
+		_, err := FSLocator{FS: os.DirFS("testfiles")}.LocateResource("missing.png")
+		Expect(errors.Is(err, fs.ErrNotExist)).To(BeTrue())
+	})
+})
@@ -0,0 +1,248 @@
+package tmx
+
+import "math"
+
+//Contains returns true if p is inside of the shape or on its outline,
+//polylines and points only contain points on them
+func (s CollisionShape) Contains(p Vertex) bool {
+	if s.Kind == ShapeEllipse {
+		if e, ok := s.ellipse(); ok {
+			return e.contains(e.local(p))
+		}
+	}
+
+	if s.isClosed() && containsVertex(s.Vertices, p) {
+		return true
+	}
+
+	return s.distance(p) < collisionEpsilon
+}
+
+//IntersectsRect returns true if the shape and the axis aligned
+//rectangle from min to max overlap or touch
+func (s CollisionShape) IntersectsRect(min, max Vertex) bool {
+	rect := []Vertex{min, {X: max.X, Y: min.Y}, max, {X: min.X, Y: max.Y}}
+	if s.Kind == ShapeEllipse {
+		if e, ok := s.ellipse(); ok {
+			//the ellipse is a unit circle in its scaled local space
+			for i, v := range rect {
+				v = e.local(v)
+				rect[i] = Vertex{X: v.X / e.a, Y: v.Y / e.b}
+			}
+
+			return containsVertex(rect, Vertex{}) || polylineDistance(rect, true, Vertex{}) <= 1
+		}
+	}
+
+	for _, v := range s.Vertices {
+		if v.X >= min.X && v.X <= max.X && v.Y >= min.Y && v.Y <= max.Y {
+			return true
+		}
+	}
+
+	if s.isClosed() {
+		for _, v := range rect {
+			if containsVertex(s.Vertices, v) {
+				return true
+			}
+		}
+	}
+
+	edges := s.edges()
+	for i := range rect {
+		for _, edge := range edges {
+			if segmentsIntersect(rect[i], rect[(i+1)%len(rect)], edge[0], edge[1]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//IntersectsCircle returns true if the shape and the circle overlap or touch
+func (s CollisionShape) IntersectsCircle(center Vertex, radius float64) bool {
+	if s.Kind == ShapeEllipse {
+		if e, ok := s.ellipse(); ok {
+			p := e.local(center)
+			return e.contains(p) || e.distance(p) <= radius+collisionEpsilon
+		}
+	}
+
+	if s.isClosed() && containsVertex(s.Vertices, center) {
+		return true
+	}
+
+	return s.distance(center) <= radius+collisionEpsilon
+}
+
+//isClosed returns true if the shape has an inside
+func (s CollisionShape) isClosed() bool {
+	switch s.Kind {
+	case ShapeRectangle, ShapeEllipse, ShapePolygon:
+		return len(s.Vertices) > 2
+	default:
+		return false
+	}
+}
+
+//edges returns the line segments of the outline of s
+func (s CollisionShape) edges() [][2]Vertex {
+	var edges [][2]Vertex
+	for i := 1; i < len(s.Vertices); i++ {
+		edges = append(edges, [2]Vertex{s.Vertices[i-1], s.Vertices[i]})
+	}
+
+	if s.isClosed() {
+		edges = append(edges, [2]Vertex{s.Vertices[len(s.Vertices)-1], s.Vertices[0]})
+	}
+
+	return edges
+}
+
+//distance returns the distance of p to the outline of s
+func (s CollisionShape) distance(p Vertex) float64 {
+	return polylineDistance(s.Vertices, s.isClosed(), p)
+}
+
+//polylineDistance returns the distance of p to the segments between
+//vertices, the last vertex is connected to the first one if closed
+func polylineDistance(vertices []Vertex, closed bool, p Vertex) float64 {
+	if len(vertices) == 1 {
+		return math.Hypot(p.X-vertices[0].X, p.Y-vertices[0].Y)
+	}
+
+	distance := math.Inf(1)
+	for i := 1; i < len(vertices); i++ {
+		distance = math.Min(distance, segmentDistance(vertices[i-1], vertices[i], p))
+	}
+
+	if closed && len(vertices) > 2 {
+		distance = math.Min(distance, segmentDistance(vertices[len(vertices)-1], vertices[0], p))
+	}
+
+	return distance
+}
+
+//segmentDistance returns the distance of p to the segment from a to b
+func segmentDistance(a, b, p Vertex) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((p.X-a.X)*dx+(p.Y-a.Y)*dy)/length))
+	}
+
+	return math.Hypot(p.X-a.X-t*dx, p.Y-a.Y-t*dy)
+}
+
+//containsVertex returns true if p is inside of the polygon using the even-odd rule
+func containsVertex(polygon []Vertex, p Vertex) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+//orientation returns the sign of the cross product of b-a and c-a
+func orientation(a, b, c Vertex) int {
+	cross := (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+	switch {
+	case cross > collisionEpsilon:
+		return 1
+	case cross < -collisionEpsilon:
+		return -1
+	default:
+		return 0
+	}
+}
+
+//segmentsIntersect returns true if the segments from a1 to a2 and b1 to b2 intersect
+func segmentsIntersect(a1, a2, b1, b2 Vertex) bool {
+	o1, o2 := orientation(a1, a2, b1), orientation(a1, a2, b2)
+	o3, o4 := orientation(b1, b2, a1), orientation(b1, b2, a2)
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+
+	//collinear segments only intersect if they overlap
+	return o1 == 0 && segmentDistance(a1, a2, b1) < collisionEpsilon ||
+		o2 == 0 && segmentDistance(a1, a2, b2) < collisionEpsilon ||
+		o3 == 0 && segmentDistance(b1, b2, a1) < collisionEpsilon ||
+		o4 == 0 && segmentDistance(b1, b2, a2) < collisionEpsilon
+}
+
+//ellipse is an ellipse shape in a local coordinate system
+//with its center at the origin and its axes along x and y
+type ellipse struct {
+	center Vertex
+	//axes are the unit vectors of the local axes
+	axes [2]Vertex
+	//a and b are the radii along the axes
+	a, b float64
+}
+
+//ellipse returns the ellipse inside of the bounding box of s,
+//ok is false if it is degenerated to a line or point
+func (s CollisionShape) ellipse() (e ellipse, ok bool) {
+	if len(s.Vertices) != 4 {
+		return e, false
+	}
+
+	v := s.Vertices
+	width := math.Hypot(v[1].X-v[0].X, v[1].Y-v[0].Y)
+	height := math.Hypot(v[3].X-v[0].X, v[3].Y-v[0].Y)
+	if width < collisionEpsilon || height < collisionEpsilon {
+		return e, false
+	}
+
+	return ellipse{
+		center: Vertex{X: (v[0].X + v[2].X) / 2, Y: (v[0].Y + v[2].Y) / 2},
+		axes: [2]Vertex{
+			{X: (v[1].X - v[0].X) / width, Y: (v[1].Y - v[0].Y) / width},
+			{X: (v[3].X - v[0].X) / height, Y: (v[3].Y - v[0].Y) / height},
+		},
+		a: width / 2,
+		b: height / 2,
+	}, true
+}
+
+//local returns p in the coordinate system of e
+func (e ellipse) local(p Vertex) Vertex {
+	x, y := p.X-e.center.X, p.Y-e.center.Y
+	return Vertex{
+		X: x*e.axes[0].X + y*e.axes[0].Y,
+		Y: x*e.axes[1].X + y*e.axes[1].Y,
+	}
+}
+
+//contains returns true if the local point p is inside of e
+func (e ellipse) contains(p Vertex) bool {
+	return (p.X*p.X)/(e.a*e.a)+(p.Y*p.Y)/(e.b*e.b) <= 1+collisionEpsilon
+}
+
+//distance returns the distance of the local point p outside of e to
+//its outline, the closest point is approximated iteratively
+func (e ellipse) distance(p Vertex) float64 {
+	px, py := math.Abs(p.X), math.Abs(p.Y)
+	tx, ty := math.Sqrt2/2, math.Sqrt2/2
+	for i := 0; i < 4; i++ {
+		x, y := e.a*tx, e.b*ty
+		ex := (e.a*e.a - e.b*e.b) * tx * tx * tx / e.a
+		ey := (e.b*e.b - e.a*e.a) * ty * ty * ty / e.b
+		r := math.Hypot(x-ex, y-ey)
+		q := math.Hypot(px-ex, py-ey)
+
+		tx = math.Max(0, math.Min(1, ((px-ex)*r/q+ex)/e.a))
+		ty = math.Max(0, math.Min(1, ((py-ey)*r/q+ey)/e.b))
+		t := math.Hypot(tx, ty)
+		tx /= t
+		ty /= t
+	}
+
+	return math.Hypot(px-e.a*tx, py-e.b*ty)
+}
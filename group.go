@@ -0,0 +1,260 @@
+package tmx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math"
+)
+
+//Group is a group layer that contains other layers
+type Group struct {
+	Name         string        `xml:"name,attr"`
+	Class        string        `xml:"class,attr,omitempty"`
+	Opacity      float32       `xml:"opacity,attr,omitempty"`
+	Visible      *visibleValue `xml:"visible,attr"`
+	OffsetX      float64       `xml:"offsetx,attr,omitempty"`
+	OffsetY      float64       `xml:"offsety,attr,omitempty"`
+	Properties   Properties    `xml:"properties,omitempty"`
+	Layers       []Layer       `xml:"layer"`
+	ObjectGroups []ObjectGroup `xml:"objectgroup"`
+	Groups       []Group       `xml:"group"`
+	//position is the position inside of the parent map or group, 0 if unknown
+	position int
+}
+
+//IsVisible returns true if the group is visible, false otherwise
+func (g Group) IsVisible() bool {
+	if g.Visible == nil {
+		return true
+	}
+
+	return g.Visible.value
+}
+
+//forEachGroup calls f for all groups inside of groups depth first
+func forEachGroup(groups []Group, f func(g *Group) error) error {
+	for i := range groups {
+		if err := f(&groups[i]); err != nil {
+			return err
+		}
+
+		if err := forEachGroup(groups[i].Groups, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//forEachLayer calls f for all tile layers of m including those in groups
+func (m *Map) forEachLayer(f func(l *Layer) error) error {
+	for i := range m.Layers {
+		if err := f(&m.Layers[i]); err != nil {
+			return err
+		}
+	}
+
+	return forEachGroup(m.Groups, func(g *Group) error {
+		for i := range g.Layers {
+			if err := f(&g.Layers[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+//forEachObjectGroup calls f for all object groups of m including those in groups
+func (m *Map) forEachObjectGroup(f func(g *ObjectGroup) error) error {
+	for i := range m.ObjectGroups {
+		if err := f(&m.ObjectGroups[i]); err != nil {
+			return err
+		}
+	}
+
+	return forEachGroup(m.Groups, func(g *Group) error {
+		for i := range g.ObjectGroups {
+			if err := f(&g.ObjectGroups[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+//groupChild is a tile layer, object group or nested group of a map
+//or group, exactly one of them is set. index is its index in the
+//slice of its kind.
+type groupChild struct {
+	layer       *Layer
+	objectGroup *ObjectGroup
+	group       *Group
+	index       int
+}
+
+//layerSlot is a tile layer in the slice of the map or group containing it
+type layerSlot struct {
+	layers *[]Layer
+	index  int
+}
+
+//layer returns the tile layer of s
+func (s layerSlot) layer() *Layer {
+	return &(*s.layers)[s.index]
+}
+
+//layerSlots returns the tile layers of m including
+//those in groups in the order they are drawn
+func (m *Map) layerSlots() []layerSlot {
+	var slots []layerSlot
+	var add func(layers *[]Layer, g *Group)
+	add = func(layers *[]Layer, g *Group) {
+		for _, child := range g.children() {
+			switch {
+			case child.layer != nil:
+				slots = append(slots, layerSlot{layers: layers, index: child.index})
+			case child.group != nil:
+				add(&child.group.Layers, child.group)
+			}
+		}
+	}
+
+	root := m.root()
+	add(&m.Layers, &root)
+	return slots
+}
+
+//AllLayers returns all tile layers of m including those in groups in the
+//order they are drawn. The index of a layer in this list is used by
+//LayerFilter, RemoveLayer and MoveLayer, the layers are only valid
+//until layers are added or removed.
+func (m *Map) AllLayers() []*Layer {
+	slots := m.layerSlots()
+	layers := make([]*Layer, len(slots))
+	for i, slot := range slots {
+		layers[i] = slot.layer()
+	}
+
+	return layers
+}
+
+//root returns a group containing the layers of m, it
+//shares the slices of m
+func (m *Map) root() Group {
+	return Group{Layers: m.Layers, ObjectGroups: m.ObjectGroups, Groups: m.Groups}
+}
+
+//children returns the tile layers, object groups and nested groups of g in
+//the order they are drawn. Loaded layers keep their position, layers
+//without one are drawn after the previous layer of the same kind or
+//after all other layers if there is none.
+func (g *Group) children() []groupChild {
+	layers := drawKeys(len(g.Layers), func(i int) int { return g.Layers[i].position })
+	objectGroups := drawKeys(len(g.ObjectGroups), func(i int) int { return g.ObjectGroups[i].position })
+	groups := drawKeys(len(g.Groups), func(i int) int { return g.Groups[i].position })
+
+	children := make([]groupChild, 0, len(layers)+len(objectGroups)+len(groups))
+	var l, o, n int
+	for len(children) < cap(children) {
+		switch {
+		case l < len(layers) && (o == len(objectGroups) || layers[l] <= objectGroups[o]) && (n == len(groups) || layers[l] <= groups[n]):
+			children = append(children, groupChild{layer: &g.Layers[l], index: l})
+			l++
+		case o < len(objectGroups) && (n == len(groups) || objectGroups[o] <= groups[n]):
+			children = append(children, groupChild{objectGroup: &g.ObjectGroups[o], index: o})
+			o++
+		default:
+			children = append(children, groupChild{group: &g.Groups[n], index: n})
+			n++
+		}
+	}
+
+	return children
+}
+
+//drawKeys returns the keys the children of one kind are drawn by,
+//children without position get the key of the previous one
+func drawKeys(count int, position func(i int) int) []int {
+	keys := make([]int, count)
+	for i := range keys {
+		keys[i] = position(i)
+		switch {
+		case keys[i] != 0:
+		case i > 0:
+			keys[i] = keys[i-1]
+		default:
+			keys[i] = math.MaxInt32
+		}
+	}
+
+	return keys
+}
+
+//readPositions sets the positions of the layers of m and
+//all of its groups from the order of the elements in the TMX data
+func (m *Map) readPositions(data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "map" {
+			root := m.root()
+			return root.readPositions(d)
+		}
+	}
+}
+
+//readPositions sets the positions of the children of g from the order
+//of the elements until the end of the current element of d
+func (g *Group) readPositions(d *xml.Decoder) error {
+	var position, l, o, n int
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "imagelayer":
+				//image layers are skipped but count like in JSON files
+				position++
+			case "layer":
+				position++
+				if l < len(g.Layers) {
+					g.Layers[l].position = position
+				}
+				l++
+			case "objectgroup":
+				position++
+				if o < len(g.ObjectGroups) {
+					g.ObjectGroups[o].position = position
+				}
+				o++
+			case "group":
+				position++
+				if n < len(g.Groups) {
+					g.Groups[n].position = position
+					if err := g.Groups[n].readPositions(d); err != nil {
+						return err
+					}
+					n++
+					continue
+				}
+				n++
+			}
+
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
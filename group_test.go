@@ -0,0 +1,125 @@
+package tmx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+//layerNames are the names of all layers in an encoded map in document order
+type layerNames []string
+
+//fromTMX reads the names of all layer elements of TMX data
+func (n *layerNames) fromTMX(data []byte) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			switch start.Name.Local {
+			case "layer", "objectgroup", "group", "imagelayer":
+				for _, attr := range start.Attr {
+					if attr.Name.Local == "name" {
+						*n = append(*n, attr.Value)
+					}
+				}
+			}
+		}
+	}
+}
+
+//namedLayer is a layer of a JSON map
+type namedLayer struct {
+	Name   string       `json:"name"`
+	Layers []namedLayer `json:"layers"`
+}
+
+//fromJSON reads the names of all layers of JSON data
+func (n *layerNames) fromJSON(data []byte) {
+	var root namedLayer
+	ExpectWithOffset(1, json.Unmarshal(data, &root)).To(Succeed())
+
+	var add func(layers []namedLayer)
+	add = func(layers []namedLayer) {
+		for _, l := range layers {
+			*n = append(*n, l.Name)
+			add(l.Layers)
+		}
+	}
+	add(root.Layers)
+}
+
+var _ = Describe("Test groups", func() {
+	var m *Map
+
+	BeforeEach(func() {
+		m = mustLoadTestMap("testfiles/layer_order.tmx")
+	})
+
+	encodedNames := func(m *Map) (tmx, tmj layerNames) {
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+		tmx.fromTMX(buffer.Bytes())
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		tmj.fromJSON(buffer.Bytes())
+
+		return tmx, tmj
+	}
+
+	It("keeps the order of interleaved layers", func() {
+		expected := layerNames{"Ground", "Items", "Walls", "Upper", "Lamps", "Roof", "Top"}
+		tmx, tmj := encodedNames(m)
+		Expect(tmx).To(Equal(expected))
+		Expect(tmj).To(Equal(expected))
+
+		var buffer bytes.Buffer
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.ObjectGroups).To(Equal(m.ObjectGroups))
+
+		tmx, tmj = encodedNames(decoded)
+		Expect(tmx).To(Equal(expected))
+		Expect(tmj).To(Equal(expected))
+	})
+
+	It("encodes and decodes the offsets of groups", func() {
+		m := mustLoadTestMap("testfiles/groups.tmx")
+		Expect(m.Groups[0].OffsetX).To(Equal(32.0))
+
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+		xmlDecoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		jsonDecoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, decoded := range []*Map{xmlDecoded, jsonDecoded} {
+			Expect(decoded.Groups[0].OffsetX).To(Equal(32.0))
+			Expect(decoded.Groups[0].Opacity).To(Equal(float32(0.5)))
+		}
+	})
+
+	It("draws added layers after the previous tile layer", func() {
+		m.AddLayer("Added")
+		Expect(m.InsertLayer(0, Layer{Name: "Inserted"})).To(Succeed())
+		Expect(m.MoveLayer(1, 2)).To(Succeed())
+
+		tmx, tmj := encodedNames(m)
+		expected := layerNames{"Inserted", "Walls", "Items", "Ground", "Upper", "Lamps", "Roof", "Top", "Added"}
+		Expect(tmx).To(Equal(expected))
+		Expect(tmj).To(Equal(expected))
+	})
+})
@@ -0,0 +1,90 @@
+package tmx_test
+
+import (
+	"image"
+	"image/png"
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/gomega"
+)
+
+//loadTestMap loads the map stored in filename
+func loadTestMap(filename string) (*Map, error) {
+	return loadTestMapWithOptions(filename, LoadOptions{})
+}
+
+//loadTestMapWithOptions loads the map stored in filename with custom LoadOptions
+func loadTestMapWithOptions(filename string, opts LoadOptions) (*Map, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewMapWithOptions(f, opts)
+}
+
+//mustLoadTestMap loads the map stored in filename and fails the spec on errors
+func mustLoadTestMap(filename string) *Map {
+	m, err := loadTestMap(filename)
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+
+	return m
+}
+
+//expectEqualMaps compares all attributes of two maps that are kept by the encoders
+func expectEqualMaps(actual, expected *Map) {
+	Expect(actual.Version).To(Equal(expected.Version))
+	Expect(actual.Orientation).To(Equal(expected.Orientation))
+	Expect(actual.RenderOrder).To(Equal(expected.RenderOrder))
+	Expect(actual.StaggerAxis).To(Equal(expected.StaggerAxis))
+	Expect(actual.StaggerIndex).To(Equal(expected.StaggerIndex))
+	Expect(actual.HexSideLength).To(Equal(expected.HexSideLength))
+	Expect(actual.Width).To(Equal(expected.Width))
+	Expect(actual.Height).To(Equal(expected.Height))
+	Expect(actual.TileWidth).To(Equal(expected.TileWidth))
+	Expect(actual.TileHeight).To(Equal(expected.TileHeight))
+	Expect(actual.BackgroundColor).To(Equal(expected.BackgroundColor))
+	Expect(actual.NextObjectID).To(Equal(expected.NextObjectID))
+	Expect(actual.Properties).To(Equal(expected.Properties))
+	Expect(actual.ObjectGroups).To(Equal(expected.ObjectGroups))
+
+	Expect(actual.Tilesets).To(HaveLen(len(expected.Tilesets)))
+	for i, tileset := range expected.Tilesets {
+		Expect(actual.Tilesets[i].FirstGID).To(Equal(tileset.FirstGID))
+		Expect(actual.Tilesets[i].Source).To(Equal(tileset.Source))
+		Expect(actual.Tilesets[i].Name).To(Equal(tileset.Name))
+		Expect(actual.Tilesets[i].Properties).To(Equal(tileset.Properties))
+		Expect(actual.Tilesets[i].Image).To(Equal(tileset.Image))
+		Expect(actual.Tilesets[i].Tiles).To(Equal(tileset.Tiles))
+	}
+
+	Expect(actual.Layers).To(HaveLen(len(expected.Layers)))
+	for i, layer := range expected.Layers {
+		Expect(actual.Layers[i].Name).To(Equal(layer.Name))
+		Expect(actual.Layers[i].Opacity).To(Equal(layer.Opacity))
+		Expect(actual.Layers[i].Visible).To(Equal(layer.Visible))
+		Expect(actual.Layers[i].Properties).To(Equal(layer.Properties))
+		Expect(actual.Layers[i].Width).To(Equal(layer.Width))
+		Expect(actual.Layers[i].Height).To(Equal(layer.Height))
+		Expect(actual.Layers[i].Data.DataTiles).To(Equal(layer.Data.DataTiles))
+	}
+}
+
+//loadTestImage loads the png image stored in filename and fails the spec on errors
+func loadTestImage(filename string) image.Image {
+	f, err := os.Open(filename)
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	defer f.Close()
+	img, err := png.Decode(f)
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+
+	return img
+}
+
+//expectSimpleExampleImage compares img to the expected rendering of simple_example_zlib.tmx
+func expectSimpleExampleImage(img image.Image) {
+	expected := loadTestImage("testfiles/simple_example_zlib_expected.png")
+	ExpectWithOffset(1, expected).To(EqualImage(img))
+}
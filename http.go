@@ -0,0 +1,168 @@
+package tmx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//HTTPLocator loads images from a web server, relative paths
+//are resolved against BaseURL. Loaded images are revalidated
+//with ETag and Last-Modified headers on every request.
+//It is safe for concurrent use.
+type HTTPLocator struct {
+	//BaseURL is used to resolve relative paths
+	BaseURL *url.URL
+	//Client sends all requests, http.DefaultClient is used if nil
+	Client *http.Client
+	//Timeout limits the duration of each request if not zero
+	Timeout time.Duration
+
+	mutex     sync.Mutex
+	resources map[string]*httpResource
+}
+
+//httpResource stores a loaded image and its validators
+type httpResource struct {
+	image        image.Image
+	etag         string
+	lastModified string
+}
+
+//HTTPError is returned if the server answers with an unexpected status
+type HTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e HTTPError) Error() string {
+	return fmt.Sprintf("could not load %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+//NewHTTPLocator creates a HTTPLocator for the given base url
+func NewHTTPLocator(baseURL string, client *http.Client) (*HTTPLocator, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPLocator{BaseURL: base, Client: client}, nil
+}
+
+//LocateResource to implement ResourceLocator interface
+func (h *HTTPLocator) LocateResource(filepath string) (image.Image, error) {
+	return h.LocateResourceContext(context.Background(), filepath)
+}
+
+//LocateResourceContext loads the image at filepath, the request
+//is canceled as soon as ctx is done
+func (h *HTTPLocator) LocateResourceContext(ctx context.Context, filepath string) (image.Image, error) {
+	target := h.resolve(filepath)
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := h.cached(target)
+	if cached != nil {
+		if cached.etag != "" {
+			request.Header.Set("If-None-Match", cached.etag)
+		}
+
+		if cached.lastModified != "" {
+			request.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.image, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, HTTPError{URL: target, StatusCode: response.StatusCode}
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	h.store(target, &httpResource{
+		image:        img,
+		etag:         response.Header.Get("ETag"),
+		lastModified: response.Header.Get("Last-Modified"),
+	})
+
+	return img, nil
+}
+
+//UnsetResource removes the cached image for filepath
+func (h *HTTPLocator) UnsetResource(filepath string) {
+	target := h.resolve(filepath)
+	h.mutex.Lock()
+	delete(h.resources, target)
+	h.mutex.Unlock()
+}
+
+//resolve returns the absolute url of filepath, characters like
+//# and ? are escaped because they are part of the file name
+func (h *HTTPLocator) resolve(path string) string {
+	reference := &url.URL{Path: filepath.ToSlash(path)}
+	if h.BaseURL == nil {
+		return reference.String()
+	}
+
+	return h.BaseURL.ResolveReference(reference).String()
+}
+
+func (h *HTTPLocator) cached(target string) *httpResource {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.resources[target]
+}
+
+func (h *HTTPLocator) store(target string, resource *httpResource) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if resource.etag == "" && resource.lastModified == "" {
+		delete(h.resources, target)
+		return
+	}
+
+	if h.resources == nil {
+		h.resources = map[string]*httpResource{}
+	}
+
+	h.resources[target] = resource
+}
@@ -0,0 +1,132 @@
+package tmx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test http resource locator", func() {
+	var (
+		server   *httptest.Server
+		mutex    sync.Mutex
+		statuses []int
+		paths    []string
+	)
+
+	record := func(path string, status int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		paths = append(paths, path)
+		statuses = append(statuses, status)
+	}
+
+	BeforeEach(func() {
+		statuses = nil
+		paths = nil
+		files := http.FileServer(http.Dir("testfiles"))
+		mux := http.NewServeMux()
+		mux.HandleFunc("/assets/", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"chipset"` {
+				record(r.URL.Path, http.StatusNotModified)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Header().Set("ETag", `"chipset"`)
+			record(r.URL.Path, http.StatusOK)
+			http.StripPrefix("/assets/", files).ServeHTTP(w, r)
+		})
+		mux.HandleFunc("/slow/", func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("resolves relative paths against the base url", func() {
+		locator, err := NewHTTPLocator(server.URL+"/assets/maps/", server.Client())
+		Expect(err).ToNot(HaveOccurred())
+		img, err := locator.LocateResource("../chipset.png")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(img.Bounds().Dx()).To(Equal(320))
+		Expect(paths).To(Equal([]string{"/assets/chipset.png"}))
+	})
+
+	It("escapes special characters of file names", func() {
+		locator, err := NewHTTPLocator(server.URL+"/assets/", server.Client())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = locator.LocateResource("tiles #1?50%.png")
+		Expect(err).To(MatchError(HTTPError{URL: server.URL + "/assets/tiles%20%231%3F50%25.png", StatusCode: http.StatusNotFound}))
+		Expect(paths).To(Equal([]string{"/assets/tiles #1?50%.png"}))
+	})
+
+	It("revalidates cached images", func() {
+		locator, err := NewHTTPLocator(server.URL+"/assets/", server.Client())
+		Expect(err).ToNot(HaveOccurred())
+		first, err := locator.LocateResource("chipset.png")
+		Expect(err).ToNot(HaveOccurred())
+		second, err := locator.LocateResource("chipset.png")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(statuses).To(Equal([]int{http.StatusOK, http.StatusNotModified}))
+
+		locator.UnsetResource("chipset.png")
+		_, err = locator.LocateResource("chipset.png")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statuses[2]).To(Equal(http.StatusOK))
+	})
+
+	It("returns http errors", func() {
+		locator, err := NewHTTPLocator(server.URL+"/assets/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = locator.LocateResource("missing.png")
+		var httpErr HTTPError
+		Expect(errors.As(err, &httpErr)).To(BeTrue())
+		Expect(httpErr.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("honors context cancellation", func() {
+		locator, err := NewHTTPLocator(server.URL+"/slow/", server.Client())
+		Expect(err).ToNot(HaveOccurred())
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = locator.LocateResourceContext(ctx, "chipset.png")
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	It("honors timeouts", func() {
+		locator, err := NewHTTPLocator(server.URL+"/slow/", server.Client())
+		Expect(err).ToNot(HaveOccurred())
+		locator.Timeout = 20 * time.Millisecond
+		_, err = locator.LocateResource("chipset.png")
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+	})
+
+	It("can be used to render maps", func() {
+		data, err := ioutil.ReadFile("./testfiles/simple_example_zlib.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		//loaded from memory, images are resolved relative to the base url
+		testMap, err := NewMap(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+
+		locator, err := NewHTTPLocator(server.URL+"/assets/", server.Client())
+		Expect(err).ToNot(HaveOccurred())
+		c := NewImageCanvasFromMap(*testMap)
+		renderer := NewRendererWithResourceLocator(*testMap, c, NewLazyResourceLocator(locator))
+		Expect(renderer.Render(0)).To(Succeed())
+		expectSimpleExampleImage(c.Image())
+	})
+})
@@ -0,0 +1,584 @@
+package tmx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//jsonMap is a map in the Tiled JSON format
+type jsonMap struct {
+	Type            string          `json:"type"`
+	Version         json.RawMessage `json:"version,omitempty"`
+	Class           string          `json:"class,omitempty"`
+	Orientation     string          `json:"orientation"`
+	RenderOrder     string          `json:"renderorder,omitempty"`
+	StaggerAxis     string          `json:"staggeraxis,omitempty"`
+	StaggerIndex    string          `json:"staggerindex,omitempty"`
+	HexSideLength   int             `json:"hexsidelength,omitempty"`
+	Width           int             `json:"width"`
+	Height          int             `json:"height"`
+	TileWidth       int             `json:"tilewidth"`
+	TileHeight      int             `json:"tileheight"`
+	BackgroundColor string          `json:"backgroundcolor,omitempty"`
+	NextObjectID    int             `json:"nextobjectid,omitempty"`
+	Properties      Properties      `json:"properties,omitempty"`
+	Tilesets        []Tileset       `json:"tilesets"`
+	Layers          []jsonLayer     `json:"layers"`
+}
+
+//jsonLayer contains the fields of all layer types,
+//tile layers, object groups and groups are supported
+type jsonLayer struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Class       string          `json:"class,omitempty"`
+	Width       int             `json:"width,omitempty"`
+	Height      int             `json:"height,omitempty"`
+	Opacity     *float32        `json:"opacity,omitempty"`
+	Visible     *bool           `json:"visible,omitempty"`
+	OffsetX     float64         `json:"offsetx,omitempty"`
+	OffsetY     float64         `json:"offsety,omitempty"`
+	Color       string          `json:"color,omitempty"`
+	Encoding    string          `json:"encoding,omitempty"`
+	Compression string          `json:"compression,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	Objects     []jsonObject    `json:"objects,omitempty"`
+	Layers      []jsonLayer     `json:"layers,omitempty"`
+	Properties  Properties      `json:"properties,omitempty"`
+}
+
+//jsonTileset is a tileset in the Tiled JSON format
+type jsonTileset struct {
+	Type             string           `json:"type,omitempty"`
+	FirstGID         GID              `json:"firstgid,omitempty"`
+	Source           string           `json:"source,omitempty"`
+	Name             string           `json:"name,omitempty"`
+	Class            string           `json:"class,omitempty"`
+	TileWidth        int              `json:"tilewidth,omitempty"`
+	TileHeight       int              `json:"tileheight,omitempty"`
+	Spacing          int              `json:"spacing,omitempty"`
+	Margin           int              `json:"margin,omitempty"`
+	Image            string           `json:"image,omitempty"`
+	ImageWidth       int              `json:"imagewidth,omitempty"`
+	ImageHeight      int              `json:"imageheight,omitempty"`
+	TransparentColor string           `json:"transparentcolor,omitempty"`
+	TileOffset       *TileOffset      `json:"tileoffset,omitempty"`
+	Transformations  *Transformations `json:"transformations,omitempty"`
+	TileCount        int              `json:"tilecount,omitempty"`
+	Columns          int              `json:"columns,omitempty"`
+	Properties       Properties       `json:"properties,omitempty"`
+	Terrains         []Terrain        `json:"terrains,omitempty"`
+	Tiles            []jsonTile       `json:"tiles,omitempty"`
+	WangSets         []WangSet        `json:"wangsets,omitempty"`
+}
+
+//jsonTile is a tile of a tileset in the Tiled JSON format
+type jsonTile struct {
+	ID          uint32     `json:"id"`
+	Type        string     `json:"type,omitempty"`
+	Class       string     `json:"class,omitempty"`
+	Probability *float64   `json:"probability,omitempty"`
+	Properties  Properties `json:"properties,omitempty"`
+	Terrain     []int      `json:"terrain,omitempty"`
+	Image       string     `json:"image,omitempty"`
+	ImageWidth  int        `json:"imagewidth,omitempty"`
+	ImageHeight int        `json:"imageheight,omitempty"`
+	Animation   []Frame    `json:"animation,omitempty"`
+	ObjectGroup *jsonLayer `json:"objectgroup,omitempty"`
+}
+
+//jsonObject is an object in the Tiled JSON format
+type jsonObject struct {
+	ID         int        `json:"id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Type       string     `json:"type,omitempty"`
+	Class      string     `json:"class,omitempty"`
+	X          float64    `json:"x"`
+	Y          float64    `json:"y"`
+	Width      float64    `json:"width,omitempty"`
+	Height     float64    `json:"height,omitempty"`
+	Rotation   float64    `json:"rotation,omitempty"`
+	GID        int        `json:"gid,omitempty"`
+	Template   string     `json:"template,omitempty"`
+	Visible    *bool      `json:"visible,omitempty"`
+	Ellipse    bool       `json:"ellipse,omitempty"`
+	Point      bool       `json:"point,omitempty"`
+	Polygon    []Vertex   `json:"polygon,omitempty"`
+	PolyLine   []Vertex   `json:"polyline,omitempty"`
+	Properties Properties `json:"properties,omitempty"`
+}
+
+//jsonTemplate is an object template in the Tiled JSON format
+type jsonTemplate struct {
+	Tileset *Tileset   `json:"tileset,omitempty"`
+	Object  jsonObject `json:"object"`
+}
+
+//jsonProperty is a custom property in the Tiled JSON format,
+//values can be strings, numbers or booleans
+type jsonProperty struct {
+	Name         string          `json:"name"`
+	Type         string          `json:"type,omitempty"`
+	PropertyType string          `json:"propertytype,omitempty"`
+	Value        json.RawMessage `json:"value"`
+}
+
+//isJSON returns true if the file stored at name with the given
+//data uses the Tiled JSON format. The extension is checked first,
+//files with unknown extensions are detected by their first byte.
+func isJSON(name string, data []byte) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".tmj", ".tsj", ".tj", ".json":
+		return true
+	case ".tmx", ".tsx", ".tx", ".xml":
+		return false
+	}
+
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
+
+//jsonValue converts a JSON value to the string
+//representation used in TMX files
+func jsonValue(raw json.RawMessage) string {
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value
+	}
+
+	if bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+
+	return string(raw)
+}
+
+//jsonOpacity converts a JSON opacity, the default value 1
+//is stored as 0 like for omitted TMX attributes
+func jsonOpacity(opacity *float32) float32 {
+	if opacity == nil || *opacity == 1 {
+		return 0
+	}
+
+	return *opacity
+}
+
+//jsonVisible converts a JSON visible flag, visible layers and
+//objects are stored like omitted TMX attributes
+func jsonVisible(visible *bool) *visibleValue {
+	if visible == nil || *visible {
+		return nil
+	}
+
+	return &visibleValue{value: false}
+}
+
+//jsonPoints converts points to the TMX points format
+func jsonPoints(points []Vertex) string {
+	values := make([]string, len(points))
+	for i, p := range points {
+		values[i] = strconv.FormatFloat(p.X, 'f', -1, 64) + "," + strconv.FormatFloat(p.Y, 'f', -1, 64)
+	}
+
+	return strings.Join(values, " ")
+}
+
+//UnmarshalJSON decodes a map in the Tiled JSON format, tile layer
+//data is decoded later like for TMX files. Image layers are
+//not supported and skipped.
+func (m *Map) UnmarshalJSON(data []byte) error {
+	var decoded jsonMap
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	if decoded.Type != "" && decoded.Type != "map" {
+		return fmt.Errorf("expected a map but got %s", decoded.Type)
+	}
+
+	*m = Map{
+		Version:         jsonValue(decoded.Version),
+		Class:           decoded.Class,
+		Orientation:     decoded.Orientation,
+		RenderOrder:     decoded.RenderOrder,
+		StaggerAxis:     decoded.StaggerAxis,
+		StaggerIndex:    decoded.StaggerIndex,
+		HexSideLength:   decoded.HexSideLength,
+		Width:           decoded.Width,
+		Height:          decoded.Height,
+		TileWidth:       decoded.TileWidth,
+		TileHeight:      decoded.TileHeight,
+		BackgroundColor: hexcolor(decoded.BackgroundColor),
+		NextObjectID:    decoded.NextObjectID,
+		Properties:      decoded.Properties,
+		Tilesets:        decoded.Tilesets,
+	}
+
+	root, err := jsonLayer{Layers: decoded.Layers}.group()
+	if err != nil {
+		return err
+	}
+
+	m.Layers = root.Layers
+	m.ObjectGroups = root.ObjectGroups
+	m.Groups = root.Groups
+
+	return nil
+}
+
+//group converts l to a group containing its supported layers
+func (l jsonLayer) group() (Group, error) {
+	group := Group{
+		Name:       l.Name,
+		Class:      l.Class,
+		Opacity:    jsonOpacity(l.Opacity),
+		Visible:    jsonVisible(l.Visible),
+		OffsetX:    l.OffsetX,
+		OffsetY:    l.OffsetY,
+		Properties: l.Properties,
+	}
+
+	for i, child := range l.Layers {
+		switch child.Type {
+		case "tilelayer":
+			layer, err := child.tileLayer()
+			if err != nil {
+				return group, err
+			}
+
+			layer.position = i + 1
+			group.Layers = append(group.Layers, layer)
+		case "objectgroup":
+			objectGroup := child.objectGroup()
+			objectGroup.position = i + 1
+			group.ObjectGroups = append(group.ObjectGroups, objectGroup)
+		case "group":
+			nested, err := child.group()
+			if err != nil {
+				return group, err
+			}
+
+			nested.position = i + 1
+			group.Groups = append(group.Groups, nested)
+		}
+	}
+
+	return group, nil
+}
+
+//tileLayer converts l to a tile layer, data is either
+//an array of gids or a base64 encoded string
+func (l jsonLayer) tileLayer() (Layer, error) {
+	layer := Layer{
+		Name:       l.Name,
+		Class:      l.Class,
+		Opacity:    jsonOpacity(l.Opacity),
+		Visible:    jsonVisible(l.Visible),
+		Properties: l.Properties,
+		Width:      l.Width,
+		Height:     l.Height,
+	}
+
+	data := bytes.TrimSpace(l.Data)
+	if len(data) == 0 {
+		return layer, nil
+	}
+
+	if data[0] == '[' {
+		var gids []GID
+		if err := json.Unmarshal(data, &gids); err != nil {
+			return layer, fmt.Errorf("invalid data of layer %s: %w", l.Name, err)
+		}
+
+		layer.Data.Encoding = "csv"
+		layer.Data.DataTiles = make([]DataTile, len(gids))
+		for i, gid := range gids {
+			layer.Data.DataTiles[i] = newDataTile(gid)
+		}
+
+		return layer, nil
+	}
+
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return layer, fmt.Errorf("invalid data of layer %s: %w", l.Name, err)
+	}
+
+	layer.Data.Encoding = l.Encoding
+	layer.Data.Compression = l.Compression
+	layer.Data.RawData = []byte(encoded)
+
+	return layer, nil
+}
+
+//objectGroup converts l to an object group
+func (l jsonLayer) objectGroup() ObjectGroup {
+	group := ObjectGroup{
+		Name:       l.Name,
+		Class:      l.Class,
+		Color:      l.Color,
+		Opacity:    jsonOpacity(l.Opacity),
+		Visible:    jsonVisible(l.Visible),
+		Properties: l.Properties,
+	}
+
+	for _, o := range l.Objects {
+		group.Objects = append(group.Objects, o.object())
+	}
+
+	return group
+}
+
+//object converts o to an Object
+func (o jsonObject) object() Object {
+	object := Object{
+		ID:         o.ID,
+		Name:       o.Name,
+		Type:       o.Type,
+		X:          o.X,
+		Y:          o.Y,
+		Width:      o.Width,
+		Height:     o.Height,
+		Rotation:   o.Rotation,
+		GID:        o.GID,
+		Template:   o.Template,
+		Visible:    jsonVisible(o.Visible),
+		Properties: o.Properties,
+	}
+
+	//Tiled 1.9 stored the type as class
+	if object.Type == "" {
+		object.Type = o.Class
+	}
+
+	if o.Ellipse {
+		object.Ellipse = &struct{}{}
+	}
+
+	if o.Point {
+		object.Point = &struct{}{}
+	}
+
+	if len(o.Polygon) > 0 {
+		object.Polygons = []Polygon{{Points: jsonPoints(o.Polygon)}}
+	}
+
+	if len(o.PolyLine) > 0 {
+		object.PolyLines = []PolyLine{{Points: jsonPoints(o.PolyLine)}}
+	}
+
+	return object
+}
+
+//UnmarshalJSON decodes an embedded or external
+//tileset in the Tiled JSON format
+func (t *Tileset) UnmarshalJSON(data []byte) error {
+	var decoded jsonTileset
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	if decoded.Type != "" && decoded.Type != "tileset" {
+		return fmt.Errorf("expected a tileset but got %s", decoded.Type)
+	}
+
+	trans := strings.TrimPrefix(decoded.TransparentColor, "#")
+	*t = Tileset{
+		FirstGID:        decoded.FirstGID,
+		Source:          decoded.Source,
+		Name:            decoded.Name,
+		Class:           decoded.Class,
+		TileWidth:       decoded.TileWidth,
+		TileHeight:      decoded.TileHeight,
+		Spacing:         decoded.Spacing,
+		Margin:          decoded.Margin,
+		TileOffset:      decoded.TileOffset,
+		Transformations: decoded.Transformations,
+		Properties:      decoded.Properties,
+		TerrainTypes:    decoded.Terrains,
+		WangSets:        decoded.WangSets,
+		Image: Image{
+			Source: decoded.Image,
+			Width:  decoded.ImageWidth,
+			Height: decoded.ImageHeight,
+		},
+	}
+
+	if decoded.Image != "" {
+		t.Image.Trans = trans
+	}
+
+	for _, tile := range decoded.Tiles {
+		decodedTile := Tile{
+			ID:          tile.ID,
+			Type:        tile.Type,
+			Class:       tile.Class,
+			Probability: tile.Probability,
+			Properties:  tile.Properties,
+			Terrain:     terrainString(tile.Terrain),
+			Image: Image{
+				Source: tile.Image,
+				Width:  tile.ImageWidth,
+				Height: tile.ImageHeight,
+			},
+		}
+
+		//tiles of collections share the transparent color of the tileset
+		if tile.Image != "" {
+			decodedTile.Image.Trans = trans
+		}
+
+		if tile.ObjectGroup != nil {
+			group := tile.ObjectGroup.objectGroup()
+			decodedTile.ObjectGroup = &group
+		}
+
+		if len(tile.Animation) > 0 {
+			decodedTile.Animation = &Animation{}
+			for i := range tile.Animation {
+				decodedTile.Animation.Frames = append(decodedTile.Animation.Frames, &tile.Animation[i])
+			}
+		}
+
+		t.Tiles = append(t.Tiles, decodedTile)
+	}
+
+	return nil
+}
+
+//terrainString converts the terrain indices of a JSON tile to the
+//terrain attribute of TMX files, -1 is written as empty value
+func terrainString(terrain []int) string {
+	if len(terrain) == 0 {
+		return ""
+	}
+
+	corners := make([]string, len(terrain))
+	for i, index := range terrain {
+		if index >= 0 {
+			corners[i] = strconv.Itoa(index)
+		}
+	}
+
+	return strings.Join(corners, ",")
+}
+
+//UnmarshalJSON decodes an object template in the Tiled JSON format
+func (t *template) UnmarshalJSON(data []byte) error {
+	var decoded jsonTemplate
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	t.Tileset = decoded.Tileset
+	t.Object = decoded.Object.object()
+
+	return nil
+}
+
+//UnmarshalJSON decodes properties in the Tiled JSON format,
+//all values are stored as strings like in TMX files
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	var decoded []jsonProperty
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	for _, property := range decoded {
+		decodedProperty := Property{
+			Name:         property.Name,
+			Type:         property.Type,
+			PropertyType: property.PropertyType,
+		}
+
+		//strings are stored without type like in TMX files
+		if decodedProperty.Type == "string" {
+			decodedProperty.Type = ""
+		}
+
+		if err := decodedProperty.unmarshalValue(property.Value); err != nil {
+			return fmt.Errorf("invalid value of property %s: %w", property.Name, err)
+		}
+
+		*p = append(*p, decodedProperty)
+	}
+
+	return nil
+}
+
+//unmarshalValue sets the value of p, objects are
+//decoded as members of a class property
+func (p *Property) unmarshalValue(raw json.RawMessage) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil
+	}
+
+	switch raw[0] {
+	case '{':
+		members, err := jsonMembers(raw)
+		if err != nil {
+			return err
+		}
+
+		p.Type = "class"
+		p.Properties = members
+		return nil
+	case 't', 'f':
+		if p.Type == "" {
+			p.Type = "bool"
+		}
+	case '"', 'n':
+	default:
+		if p.Type == "" {
+			p.Type = "float"
+			if _, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+				p.Type = "int"
+			}
+		}
+	}
+
+	p.Value = jsonValue(raw)
+
+	return nil
+}
+
+//jsonMembers decodes the members of a class value in their original order,
+//members only contain values so their types are guessed
+func jsonMembers(raw json.RawMessage) (Properties, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	var members Properties
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid member name %v", token)
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		member := Property{Name: name}
+		if err := member.unmarshalValue(value); err != nil {
+			return nil, err
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
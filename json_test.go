@@ -0,0 +1,142 @@
+package tmx_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing/fstest"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test JSON map format", func() {
+	withoutTemplates := func(groups []ObjectGroup) []ObjectGroup {
+		result := make([]ObjectGroup, len(groups))
+		for i, group := range groups {
+			result[i] = group
+			result[i].Objects = make([]Object, len(group.Objects))
+			for j, object := range group.Objects {
+				object.Template = ""
+				result[i].Objects[j] = object
+			}
+		}
+
+		return result
+	}
+
+	expectParity := func(jsonMap, xmlMap *Map) {
+		Expect(jsonMap.Orientation).To(Equal(xmlMap.Orientation))
+		Expect(jsonMap.RenderOrder).To(Equal(xmlMap.RenderOrder))
+		Expect(jsonMap.Width).To(Equal(xmlMap.Width))
+		Expect(jsonMap.Height).To(Equal(xmlMap.Height))
+		Expect(jsonMap.TileWidth).To(Equal(xmlMap.TileWidth))
+		Expect(jsonMap.TileHeight).To(Equal(xmlMap.TileHeight))
+		Expect(jsonMap.BackgroundColor).To(Equal(xmlMap.BackgroundColor))
+		Expect(jsonMap.NextObjectID).To(Equal(xmlMap.NextObjectID))
+		Expect(jsonMap.Properties).To(Equal(xmlMap.Properties))
+		Expect(withoutTemplates(jsonMap.ObjectGroups)).To(Equal(withoutTemplates(xmlMap.ObjectGroups)))
+
+		Expect(jsonMap.Tilesets).To(HaveLen(len(xmlMap.Tilesets)))
+		for i, tileset := range xmlMap.Tilesets {
+			Expect(jsonMap.Tilesets[i].FirstGID).To(Equal(tileset.FirstGID))
+			Expect(jsonMap.Tilesets[i].Name).To(Equal(tileset.Name))
+			Expect(jsonMap.Tilesets[i].TileWidth).To(Equal(tileset.TileWidth))
+			Expect(jsonMap.Tilesets[i].TileHeight).To(Equal(tileset.TileHeight))
+			Expect(jsonMap.Tilesets[i].Properties).To(Equal(tileset.Properties))
+			Expect(jsonMap.Tilesets[i].Image).To(Equal(tileset.Image))
+			Expect(jsonMap.Tilesets[i].Tiles).To(Equal(tileset.Tiles))
+		}
+
+		Expect(jsonMap.Layers).To(HaveLen(len(xmlMap.Layers)))
+		for i, layer := range xmlMap.Layers {
+			Expect(jsonMap.Layers[i].Name).To(Equal(layer.Name))
+			Expect(jsonMap.Layers[i].Opacity).To(Equal(layer.Opacity))
+			Expect(jsonMap.Layers[i].Visible).To(Equal(layer.Visible))
+			Expect(jsonMap.Layers[i].Properties).To(Equal(layer.Properties))
+			Expect(jsonMap.Layers[i].Width).To(Equal(layer.Width))
+			Expect(jsonMap.Layers[i].Height).To(Equal(layer.Height))
+			Expect(jsonMap.Layers[i].Data.DataTiles).To(Equal(layer.Data.DataTiles))
+		}
+	}
+
+	for _, name := range []string{
+		"simple_example",
+		"simple_example_zlib",
+		"animated_example_zlib",
+		"uncompressed_not_square",
+		"transparent_example",
+		"external/external_example",
+	} {
+		name := name
+		It("loads the same map from "+name+".tmj and "+name+".tmx", func() {
+			jsonMap := mustLoadTestMap("testfiles/" + name + ".tmj")
+			Expect(jsonMap.Version).To(Equal("1.10"))
+			expectParity(jsonMap, mustLoadTestMap("testfiles/"+name+".tmx"))
+		})
+	}
+
+	It("detects JSON maps by their first byte", func() {
+		data, err := ioutil.ReadFile("testfiles/simple_example_zlib.tmj")
+		Expect(err).ToNot(HaveOccurred())
+		jsonMap, err := NewMap(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		expectParity(jsonMap, mustLoadTestMap("testfiles/simple_example_zlib.tmx"))
+	})
+
+	It("loads JSON maps with external files from a fs.FS", func() {
+		fsys := fstest.MapFS{}
+		for _, name := range []string{
+			"external/external_example.tmj",
+			"tilesets/chipset.tsj",
+			"templates/chest.tj",
+			"chipset.png",
+		} {
+			data, err := ioutil.ReadFile("testfiles/" + name)
+			Expect(err).ToNot(HaveOccurred())
+			fsys[name] = &fstest.MapFile{Data: data}
+		}
+
+		m, err := LoadMapFS(fsys, "external/external_example.tmj")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Tilesets[0].GetTileByID(32)).ToNot(BeNil())
+		Expect(m.ObjectGroups[0].Objects[0].GID).To(Equal(5))
+		Expect(m.ObjectGroups[0].Objects[1].Properties).To(ConsistOf(
+			Property{Name: "content", Value: "diamonds"},
+			Property{Name: "locked", Type: "bool", Value: "false"},
+		))
+
+		c := NewImageCanvasFromMap(*m)
+		Expect(NewRendererWithResourceLocator(*m, c, FSLocator{FS: fsys}).Render(0)).To(Succeed())
+		expectSimpleExampleImage(c.Image())
+	})
+
+	It("stores property values as strings with their type", func() {
+		m, err := NewMap(strings.NewReader(`{
+			"type": "map", "version": 1, "width": 1, "height": 1,
+			"properties": [
+				{"name": "count", "type": "int", "value": 3},
+				{"name": "speed", "type": "float", "value": 1.5},
+				{"name": "solid", "type": "bool", "value": true},
+				{"name": "label", "type": "string", "value": "door"}
+			]
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Version).To(Equal("1"))
+		Expect(m.Properties).To(Equal(Properties{
+			{Name: "count", Type: "int", Value: "3"},
+			{Name: "speed", Type: "float", Value: "1.5"},
+			{Name: "solid", Type: "bool", Value: "true"},
+			{Name: "label", Value: "door"},
+		}))
+	})
+
+	It("fails for invalid JSON files", func() {
+		_, err := NewMap(strings.NewReader(`{"type": "tileset"}`))
+		Expect(err).To(HaveOccurred())
+
+		_, err = NewMap(strings.NewReader(`{"layers": [{"type": "tilelayer", "data": {}}]}`))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -2,7 +2,12 @@
 // specification: http://doc.mapeditor.org/reference/tmx-map-format/
 package tmx
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 const (
 	//GIDHorizontalFlip for horizontal flipped tiles
@@ -30,6 +35,29 @@ type DataTile struct {
 	DiagonalFlip bool
 }
 
+//flips returns the flip flags of this tile
+func (d DataTile) flips() GID {
+	var flips GID
+	if d.HorizontalFlip {
+		flips |= GIDHorizontalFlip
+	}
+
+	if d.VerticalFlip {
+		flips |= GIDVerticalFlip
+	}
+
+	if d.DiagonalFlip {
+		flips |= GIDDiagonalFlip
+	}
+
+	return flips
+}
+
+//rawGID returns the gid including the flip flags
+func (d DataTile) rawGID() GID {
+	return d.GID | d.flips()
+}
+
 //visibleValue must be used since the stupid default for visible is true
 type visibleValue struct {
 	value bool
@@ -49,12 +77,15 @@ func (v *visibleValue) UnmarshalXMLAttr(attr xml.Attr) error {
 //Layer represents one layer of the map.
 type Layer struct {
 	Name       string        `xml:"name,attr"`
-	Opacity    float32       `xml:"opacity,attr"`
+	Class      string        `xml:"class,attr,omitempty"`
+	Opacity    float32       `xml:"opacity,attr,omitempty"`
 	Visible    *visibleValue `xml:"visible,attr"`
-	Properties []Property    `xml:"properties>property"`
+	Properties Properties    `xml:"properties,omitempty"`
 	Data       Data          `xml:"data"`
 	Width      int           `xml:"width,attr"`
 	Height     int           `xml:"height,attr"`
+	//position is the position inside of the parent map or group, 0 if unknown
+	position int
 }
 
 //IsVisible returns true if the layer is visible, false otherwise
@@ -69,11 +100,14 @@ func (l Layer) IsVisible() bool {
 //ObjectGroup is a group of objects
 type ObjectGroup struct {
 	Name       string        `xml:"name,attr"`
-	Color      string        `xml:"color,attr"`
-	Opacity    float32       `xml:"opacity,attr"`
+	Class      string        `xml:"class,attr,omitempty"`
+	Color      string        `xml:"color,attr,omitempty"`
+	Opacity    float32       `xml:"opacity,attr,omitempty"`
 	Visible    *visibleValue `xml:"visible,attr"`
-	Properties []Property    `xml:"properties>property"`
+	Properties Properties    `xml:"properties,omitempty"`
 	Objects    []Object      `xml:"object"`
+	//position is the position inside of the parent map or group, 0 if unknown
+	position int
 }
 
 //IsVisible returns true if the object group is visible, false otherwise
@@ -87,17 +121,22 @@ func (o ObjectGroup) IsVisible() bool {
 
 // Object is an object
 type Object struct {
-	Name       string        `xml:"name,attr"`
-	Type       string        `xml:"type,attr"`
-	X          int           `xml:"x,attr"`
-	Y          int           `xml:"y,attr"`
-	Width      int           `xml:"width,attr"`
-	Height     int           `xml:"height,attr"`
-	GID        int           `xml:"gid,attr"`
+	ID         int           `xml:"id,attr,omitempty"`
+	Name       string        `xml:"name,attr,omitempty"`
+	Type       string        `xml:"type,attr,omitempty"`
+	X          float64       `xml:"x,attr"`
+	Y          float64       `xml:"y,attr"`
+	Width      float64       `xml:"width,attr,omitempty"`
+	Height     float64       `xml:"height,attr,omitempty"`
+	Rotation   float64       `xml:"rotation,attr,omitempty"`
+	GID        int           `xml:"gid,attr,omitempty"`
+	Template   string        `xml:"template,attr,omitempty"`
 	Visible    *visibleValue `xml:"visible,attr"`
+	Ellipse    *struct{}     `xml:"ellipse"`
+	Point      *struct{}     `xml:"point"`
 	Polygons   []Polygon     `xml:"polygon"`
 	PolyLines  []PolyLine    `xml:"polyline"`
-	Properties []Property    `xml:"properties>property"`
+	Properties Properties    `xml:"properties,omitempty"`
 }
 
 //IsVisible returns true if the object is visible, false otherwise
@@ -114,13 +153,61 @@ type Polygon struct {
 	Points string `xml:"points,attr"`
 }
 
+//Vertices returns the points of the polygon relative to its object
+func (p Polygon) Vertices() ([]Vertex, error) {
+	return parseVertices(p.Points)
+}
+
 //PolyLine loads a polyline from tmx
 type PolyLine struct {
 	Points string `xml:"points,attr"`
 }
 
+//Vertices returns the points of the polyline relative to its object
+func (p PolyLine) Vertices() ([]Vertex, error) {
+	return parseVertices(p.Points)
+}
+
+//Vertex is a point of a shape in pixels
+type Vertex struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+//parseVertices parses space separated x,y pairs
+func parseVertices(points string) ([]Vertex, error) {
+	var result []Vertex
+	for _, point := range strings.Fields(points) {
+		coordinates := strings.Split(point, ",")
+		if len(coordinates) != 2 {
+			return nil, fmt.Errorf("invalid point %s", point)
+		}
+
+		x, err := strconv.ParseFloat(coordinates[0], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := strconv.ParseFloat(coordinates[1], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, Vertex{X: x, Y: y})
+	}
+
+	return result, nil
+}
+
 //Property can be set on tiles
 type Property struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value,attr"`
+	Name string `xml:"name,attr"`
+	//Type is string, int, float, bool, color, file, object or class,
+	//an empty type is a string
+	Type string `xml:"type,attr,omitempty"`
+	//PropertyType is the name of the custom enum or class
+	PropertyType string `xml:"propertytype,attr,omitempty"`
+	Value        string `xml:"value,attr,omitempty"`
+	//Properties contains the members of class properties
+	Properties Properties `xml:"properties,omitempty"`
 }
@@ -0,0 +1,105 @@
+package tmx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+//errFound stops the iteration of object groups
+var errFound = errors.New("found")
+
+//FindObjects returns all objects for which matches returns true. Object groups
+//of the map are searched before those in groups, nested groups are searched
+//depth first. The returned objects point into the object groups of the map.
+func (m *Map) FindObjects(matches func(o *Object) bool) []*Object {
+	var result []*Object
+	m.forEachObjectGroup(func(g *ObjectGroup) error {
+		for i := range g.Objects {
+			if matches(&g.Objects[i]) {
+				result = append(result, &g.Objects[i])
+			}
+		}
+
+		return nil
+	})
+
+	return result
+}
+
+//GetObjectByID returns the object with the given id or nil if there is none,
+//objects without id are never returned
+func (m *Map) GetObjectByID(id int) *Object {
+	if id <= 0 {
+		return nil
+	}
+
+	var result *Object
+	m.forEachObjectGroup(func(g *ObjectGroup) error {
+		for i := range g.Objects {
+			if g.Objects[i].ID == id {
+				result = &g.Objects[i]
+				return errFound
+			}
+		}
+
+		return nil
+	})
+
+	return result
+}
+
+//GetObjectsByName returns all objects with the given name
+func (m *Map) GetObjectsByName(name string) []*Object {
+	return m.FindObjects(func(o *Object) bool {
+		return o.Name == name
+	})
+}
+
+//GetObjectsByType returns all objects with the given type, which is called class since Tiled 1.9
+func (m *Map) GetObjectsByType(objectType string) []*Object {
+	return m.FindObjects(func(o *Object) bool {
+		return o.Type == objectType
+	})
+}
+
+//GetObjectsByProperty returns all objects whose property with the given name has the value
+func (m *Map) GetObjectsByProperty(name, value string) []*Object {
+	return m.FindObjects(func(o *Object) bool {
+		v, ok := o.Properties.Get(name)
+		return ok && v == value
+	})
+}
+
+//GetObjectProperty returns the object referenced by the object property with
+//the given name of p. Nil is returned for empty references, an error is returned
+//if the property is missing, has another type or the object doesn't exist.
+func (m *Map) GetObjectProperty(p Properties, name string) (*Object, error) {
+	for _, property := range p {
+		if property.Name != name {
+			continue
+		}
+
+		if property.Type != "object" {
+			return nil, fmt.Errorf("property %s is no object reference", name)
+		}
+
+		if property.Value == "" || property.Value == "0" {
+			return nil, nil
+		}
+
+		id, err := strconv.Atoi(property.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object reference %s: %w", name, err)
+		}
+
+		o := m.GetObjectByID(id)
+		if o == nil {
+			return nil, fmt.Errorf("object %d referenced by %s not found", id, name)
+		}
+
+		return o, nil
+	}
+
+	return nil, fmt.Errorf("property %s not found", name)
+}
@@ -0,0 +1,110 @@
+package tmx_test
+
+import (
+	"bytes"
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test object lookups", func() {
+	var m *Map
+
+	ids := func(objects []*Object) []int {
+		result := []int{}
+		for _, o := range objects {
+			result = append(result, o.ID)
+		}
+
+		return result
+	}
+
+	BeforeEach(func() {
+		f, err := os.Open("testfiles/object_lookup.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		m, err = NewMap(f)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("loads nested groups", func() {
+		Expect(m.Groups).To(HaveLen(1))
+		level := m.Groups[0]
+		Expect(level.Name).To(Equal("Level"))
+		Expect(level.Opacity).To(Equal(float32(0.5)))
+		Expect(level.IsVisible()).To(BeTrue())
+		Expect(level.Properties).To(Equal(Properties{{Name: "floor", Type: "int", Value: "1"}}))
+		Expect(level.Layers[0].Data.DataTiles).To(Equal([]DataTile{{GID: 0}, {GID: 2}, {GID: 3}, {GID: 0}}))
+		Expect(level.Groups[0].Name).To(Equal("Secret"))
+		Expect(level.Groups[0].IsVisible()).To(BeFalse())
+
+		usage, err := m.TilesetUsage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage[0].Tiles).To(Equal(map[uint32]int{0: 4, 1: 1, 2: 1}))
+	})
+
+	It("finds objects by id in all groups", func() {
+		Expect(m.GetObjectByID(1).Name).To(Equal("door"))
+		Expect(m.GetObjectByID(5).Name).To(Equal("exit"))
+		Expect(m.GetObjectByID(4)).To(BeNil())
+
+		m.ObjectGroups[0].Objects = append(m.ObjectGroups[0].Objects, Object{Name: "unsaved"})
+		Expect(m.GetObjectByID(0)).To(BeNil())
+		Expect(m.GetObjectByID(-1)).To(BeNil())
+
+		m.GetObjectByID(3).X = 99
+		Expect(m.Groups[0].ObjectGroups[0].Objects[0].X).To(Equal(99.0))
+	})
+
+	It("finds objects by name, type and property", func() {
+		Expect(ids(m.GetObjectsByName("key"))).To(Equal([]int{3, 6}))
+		Expect(ids(m.GetObjectsByName("chest"))).To(BeEmpty())
+		Expect(ids(m.GetObjectsByType("Door"))).To(Equal([]int{1, 5}))
+		Expect(ids(m.GetObjectsByProperty("color", "blue"))).To(Equal([]int{6}))
+		Expect(ids(m.GetObjectsByProperty("locked", "false"))).To(Equal([]int{5}))
+		Expect(ids(m.FindObjects(func(o *Object) bool {
+			return o.X >= 32
+		}))).To(Equal([]int{2, 5, 6}))
+	})
+
+	It("resolves object properties", func() {
+		door := m.GetObjectByID(1)
+		target, err := m.GetObjectProperty(door.Properties, "target")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal(m.GetObjectByID(5)))
+
+		key := m.GetObjectByID(6)
+		owner, err := m.GetObjectProperty(key.Properties, "owner")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(owner).To(BeNil())
+
+		for _, name := range []string{"broken", "color", "missing"} {
+			_, err := m.GetObjectProperty(key.Properties, name)
+			Expect(err).To(HaveOccurred(), name)
+		}
+	})
+
+	It("encodes and decodes groups", func() {
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{Encoding: "csv"})).To(Succeed())
+		xmlDecoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{Encoding: "base64", Compression: "zlib"})).To(Succeed())
+		jsonDecoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, decoded := range []*Map{xmlDecoded, jsonDecoded} {
+			Expect(decoded.Groups).To(HaveLen(1))
+			Expect(decoded.Groups[0].Properties).To(Equal(m.Groups[0].Properties))
+			Expect(decoded.Groups[0].Opacity).To(Equal(m.Groups[0].Opacity))
+			Expect(decoded.Groups[0].Layers[0].Data.DataTiles).To(Equal(m.Groups[0].Layers[0].Data.DataTiles))
+			Expect(decoded.Groups[0].ObjectGroups).To(Equal(m.Groups[0].ObjectGroups))
+			Expect(decoded.Groups[0].Groups[0].IsVisible()).To(BeFalse())
+			Expect(decoded.Groups[0].Groups[0].ObjectGroups).To(Equal(m.Groups[0].Groups[0].ObjectGroups))
+		}
+	})
+})
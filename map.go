@@ -1,31 +1,80 @@
 package tmx
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 //Map contains map information
 type Map struct {
-	Version         string        `xml:"title,attr"`
+	Version         string        `xml:"version,attr,omitempty"`
+	Class           string        `xml:"class,attr,omitempty"`
 	Orientation     string        `xml:"orientation,attr"`
+	RenderOrder     string        `xml:"renderorder,attr,omitempty"`
+	StaggerAxis     string        `xml:"staggeraxis,attr,omitempty"`
+	StaggerIndex    string        `xml:"staggerindex,attr,omitempty"`
+	HexSideLength   int           `xml:"hexsidelength,attr,omitempty"`
 	Width           int           `xml:"width,attr"`
 	Height          int           `xml:"height,attr"`
 	TileWidth       int           `xml:"tilewidth,attr"`
 	TileHeight      int           `xml:"tileheight,attr"`
-	BackgroundColor hexcolor      `xml:"backgroundcolor,attr"`
-	RenderOrder     string        `xml:"renderorder,attr"`
-	Properties      []Property    `xml:"properties>property"`
+	BackgroundColor hexcolor      `xml:"backgroundcolor,attr,omitempty"`
+	NextObjectID    int           `xml:"nextobjectid,attr,omitempty"`
+	Properties      Properties    `xml:"properties,omitempty"`
 	Tilesets        []Tileset     `xml:"tileset"`
 	Layers          []Layer       `xml:"layer"`
 	ObjectGroups    []ObjectGroup `xml:"objectgroup"`
+	Groups          []Group       `xml:"group"`
 	//since tileset loading sucks so much and uses relative paths
-	//we store the original filename for this map if possible
+	//we store the original slash separated filename for this map if possible
 	filename string
+	//resolver resolves paths relative to filename
+	resolver PathResolver
+	//gidIndex contains the gid ranges of all tilesets sorted by FirstGID
+	gidIndex []gidRange
+}
+
+//gidRange contains all gids of the tileset at index
+type gidRange struct {
+	firstGID GID
+	endGID   GID
+	index    int
+}
+
+//BuildIndex speeds up GetTilesetForGID and GetTileByID, it is called by
+//NewMap. Changed tilesets and tiles are detected and searched without
+//the index, calling it again after changes restores the speed.
+func (m *Map) BuildIndex() {
+	index := make([]gidRange, len(m.Tilesets))
+	for i := range m.Tilesets {
+		m.Tilesets[i].buildIndex()
+		index[i] = gidRange{
+			firstGID: m.Tilesets[i].FirstGID,
+			endGID:   m.Tilesets[i].FirstGID + GID(m.Tilesets[i].GetNumTiles()),
+			index:    i,
+		}
+	}
+
+	sort.SliceStable(index, func(i, j int) bool {
+		return index[i].firstGID < index[j].firstGID
+	})
+
+	//overlapping tilesets can't be searched, the first
+	//matching tileset wins in this case
+	for i := 1; i < len(index); i++ {
+		if index[i].firstGID < index[i-1].endGID {
+			m.gidIndex = nil
+			return
+		}
+	}
+
+	m.gidIndex = index
 }
 
 //GetTilesetForGID returns the correct tileset for a given gid
@@ -34,8 +83,12 @@ func (m Map) GetTilesetForGID(gid GID) (*Tileset, error) {
 		return nil, nil
 	}
 
+	if tileset, ok := m.searchTileset(gid); ok {
+		return tileset, nil
+	}
+
 	for i, tileset := range m.Tilesets {
-		if gid >= tileset.FirstGID && gid < tileset.FirstGID+GID(tileset.GetNumTiles()) {
+		if tileset.containsGID(gid) {
 			return &m.Tilesets[i], nil
 		}
 	}
@@ -43,33 +96,144 @@ func (m Map) GetTilesetForGID(gid GID) (*Tileset, error) {
 	return nil, fmt.Errorf("Invalid GID %d given.", gid)
 }
 
-// NewMap creates a new map from a given io.Reader
+//GetTileForGID returns the tile information of gid, nil is returned
+//for empty gids and tiles without any information in their tileset
+func (m Map) GetTileForGID(gid GID) (*Tile, error) {
+	tileset, err := m.GetTilesetForGID(gid)
+	if err != nil || tileset == nil {
+		return nil, err
+	}
+
+	return tileset.GetTileByID(uint32(gid - tileset.FirstGID)), nil
+}
+
+//TileAt returns the tile information at position x, y of layer l
+func (m Map) TileAt(l Layer, x, y int) (*Tile, error) {
+	tile, err := l.TileAt(x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.GetTileForGID(tile.GID)
+}
+
+//TilePropertiesAt returns the properties of the tile at position x, y of layer l
+func (m Map) TilePropertiesAt(l Layer, x, y int) (Properties, error) {
+	tile, err := m.TileAt(l, x, y)
+	if err != nil || tile == nil {
+		return nil, err
+	}
+
+	return tile.Properties, nil
+}
+
+//TileClassAt returns the class of the tile at position x, y of layer l
+func (m Map) TileClassAt(l Layer, x, y int) (string, error) {
+	tile, err := m.TileAt(l, x, y)
+	if err != nil || tile == nil {
+		return "", err
+	}
+
+	return tile.GetClass(), nil
+}
+
+//searchTileset uses the index to find the tileset for gid,
+//ok is false if the index is missing or outdated
+func (m Map) searchTileset(gid GID) (tileset *Tileset, ok bool) {
+	if len(m.gidIndex) == 0 || len(m.gidIndex) != len(m.Tilesets) {
+		return nil, false
+	}
+
+	i := sort.Search(len(m.gidIndex), func(i int) bool {
+		return m.gidIndex[i].firstGID > gid
+	}) - 1
+	if i < 0 {
+		return nil, false
+	}
+
+	r := m.gidIndex[i]
+	if m.Tilesets[r.index].FirstGID != r.firstGID || !m.Tilesets[r.index].containsGID(gid) {
+		return nil, false
+	}
+
+	return &m.Tilesets[r.index], true
+}
+
+//LoadOptions configure how maps and their external files are loaded
+type LoadOptions struct {
+	//PathResolver resolves the paths of external tilesets, templates
+	//and images, defaults to DefaultPathResolver. It is also
+	//used by the renderer unless RenderOptions specify another one.
+	PathResolver PathResolver
+	//Project resolves custom classes and enums of all
+	//properties after the map has been loaded if set
+	Project *Project
+}
+
+// NewMap creates a new map from a given io.Reader,
+// external tilesets and templates are only loaded
+// if f is an *os.File. Maps in the Tiled JSON format
+// are detected by their extension or first byte.
 func NewMap(f io.Reader) (*Map, error) {
-	var target Map
+	return NewMapWithOptions(f, LoadOptions{})
+}
+
+// NewMapWithOptions creates a new map from a given io.Reader
+// like NewMap with custom LoadOptions
+func NewMapWithOptions(f io.Reader, opts LoadOptions) (*Map, error) {
 	data, err := ioutil.ReadAll(f)
 	if err != nil {
 		return nil, err
 	}
 
 	filename := ""
+	var files externalFiles
 	if f, ok := f.(*os.File); ok {
-		filename = filepath.Dir(f.Name()) + string(os.PathSeparator)
+		filename = filepath.ToSlash(f.Name())
+		files = osFiles{}
+	}
+
+	return decodeMap(data, filename, files, opts)
+}
+
+//decodeMap decodes the TMX or JSON data of a map, files is used
+//to load external tilesets and templates if not nil
+func decodeMap(data []byte, filename string, files externalFiles, opts LoadOptions) (*Map, error) {
+	var target Map
+	var err error
+	if isJSON(filename, data) {
+		err = json.Unmarshal(data, &target)
+	} else {
+		err = xml.Unmarshal(data, &target)
+		if err == nil {
+			err = target.readPositions(data)
+		}
 	}
 
-	err = xml.Unmarshal(data, &target)
 	if err != nil {
 		return nil, err
 	}
 
 	target.filename = filename
+	target.resolver = resolverOrDefault(opts.PathResolver)
 
-	for key, layer := range target.Layers {
-		if e := layer.Data.loadEncodedTiles(); e != nil {
-			return nil, e
+	if err := target.forEachLayer(func(l *Layer) error {
+		return l.Data.loadEncodedTiles()
+	}); err != nil {
+		return nil, err
+	}
+
+	if files != nil {
+		if err := loadExternals(&target, files); err != nil {
+			return nil, err
 		}
+	}
 
-		target.Layers[key] = layer
+	if opts.Project != nil {
+		opts.Project.resolveMap(&target)
 	}
 
+	target.BuildIndex()
+
 	return &target, nil
 }
@@ -1,6 +1,9 @@
 package tmx_test
 
 import (
+	"bytes"
+	"os"
+
 	. "github.com/manyminds/tmx"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -68,4 +71,170 @@ var _ = Describe("Tests for map", func() {
 			Expect(set).To(Equal(&giantTileset))
 		})
 	})
+
+	Context("Test indexed lookups", func() {
+		var testMap *Map
+
+		BeforeEach(func() {
+			var err error
+			testMap, err = loadTestMap("testfiles/simple_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("finds tilesets at their boundaries", func() {
+			for gid, expected := range map[GID]int{1: 0, 500: 0, 501: 1, 1000: 1} {
+				set, err := testMap.GetTilesetForGID(gid)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(set).To(BeIdenticalTo(&testMap.Tilesets[expected]))
+			}
+		})
+
+		It("errors outside of all tilesets", func() {
+			set, err := testMap.GetTilesetForGID(1001)
+			Expect(err).To(HaveOccurred())
+			Expect(set).To(BeNil())
+		})
+
+		It("notices changed tilesets", func() {
+			testMap.Tilesets = testMap.Tilesets[:1]
+			_, err := testMap.GetTilesetForGID(501)
+			Expect(err).To(HaveOccurred())
+
+			testMap.Tilesets[0].FirstGID = 11
+			set, err := testMap.GetTilesetForGID(505)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(set).To(BeIdenticalTo(&testMap.Tilesets[0]))
+		})
+
+		It("notices changed tiles and tileset sizes", func() {
+			m := Map{Tilesets: []Tileset{
+				{FirstGID: 1, TileWidth: 32, TileHeight: 32, Image: Image{Source: "tiles.png", Width: 320, Height: 320}, Tiles: []Tile{{ID: 1}, {ID: 2}}},
+			}}
+			m.BuildIndex()
+
+			m.Tilesets[0].Tiles[1].ID = 7
+			Expect(m.Tilesets[0].GetTileByID(2)).To(BeNil())
+			Expect(m.Tilesets[0].GetTileByID(7)).To(BeIdenticalTo(&m.Tilesets[0].Tiles[1]))
+			tile, err := m.GetTileForGID(8)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tile).To(BeIdenticalTo(&m.Tilesets[0].Tiles[1]))
+
+			m.Tilesets[0].Image.Height = 32
+			_, err = m.GetTilesetForGID(11)
+			Expect(err).To(HaveOccurred())
+			set, err := m.GetTilesetForGID(10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(set).To(BeIdenticalTo(&m.Tilesets[0]))
+		})
+
+		It("returns the first tileset for overlapping tilesets after BuildIndex", func() {
+			m := Map{Tilesets: []Tileset{
+				{FirstGID: 1, TileWidth: 32, TileHeight: 32, Image: Image{Width: 320, Height: 320}},
+				{FirstGID: 100, TileWidth: 32, TileHeight: 32, Image: Image{Width: 320, Height: 320}},
+			}}
+			m.BuildIndex()
+			set, err := m.GetTilesetForGID(100)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(set).To(BeIdenticalTo(&m.Tilesets[0]))
+		})
+
+		It("returns tiles stored in the tileset", func() {
+			tileset := testMap.Tilesets[0]
+			tile := tileset.GetTileByID(1)
+			Expect(tile).To(BeIdenticalTo(&testMap.Tilesets[0].Tiles[1]))
+			Expect(tileset.GetTileByID(2)).To(BeNil())
+
+			tile.Animation = &Animation{}
+			Expect(testMap.Tilesets[0].Tiles[1].Animation).ToNot(BeNil())
+		})
+
+		It("finds tiles of tilesets without index", func() {
+			tileset := Tileset{Tiles: []Tile{{ID: 3}, {ID: 7}}}
+			Expect(tileset.GetTileByID(7)).To(BeIdenticalTo(&tileset.Tiles[1]))
+			Expect(tileset.GetTileByID(4)).To(BeNil())
+		})
+	})
+
+	Context("Test tile information", func() {
+		var m *Map
+
+		BeforeEach(func() {
+			f, err := os.Open("testfiles/tile_classes.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			m, err = NewMap(f)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("loads classes, probabilities and properties of tiles", func() {
+			tiles := m.Tilesets[0].Tiles
+			Expect(tiles).To(HaveLen(3))
+			Expect(tiles[0].GetClass()).To(Equal("water"))
+			Expect(tiles[0].GetProbability()).To(Equal(0.5))
+			swimmable, ok := tiles[0].Properties.Get("swimmable")
+			Expect(ok).To(BeTrue())
+			Expect(swimmable).To(Equal("true"))
+			Expect(tiles[1].GetClass()).To(Equal("lava"))
+			Expect(tiles[1].GetProbability()).To(Equal(1.0))
+			Expect(tiles[2].GetClass()).To(BeEmpty())
+			Expect(tiles[2].GetProbability()).To(BeZero())
+		})
+
+		It("returns the tile information at a position", func() {
+			layer := m.Layers[0]
+			tile, err := m.TileAt(layer, 1, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tile).To(BeIdenticalTo(&m.Tilesets[0].Tiles[1]))
+
+			properties, err := m.TilePropertiesAt(layer, 0, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(properties).To(Equal(Properties{
+				{Name: "speed", Type: "float", Value: "0.5"},
+				{Name: "swimmable", Type: "bool", Value: "true"},
+			}))
+
+			//flipped tiles share the information of the tile
+			class, err := m.TileClassAt(layer, 2, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(class).To(Equal("water"))
+
+			class, err = m.TileClassAt(layer, 1, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(class).To(Equal("lava"))
+		})
+
+		It("returns nothing for empty tiles and tiles without information", func() {
+			for _, position := range [][2]int{{1, 1}, {0, 1}} {
+				tile, err := m.TileAt(m.Layers[0], position[0], position[1])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(tile).To(BeNil())
+
+				properties, err := m.TilePropertiesAt(m.Layers[0], position[0], position[1])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(properties).To(BeNil())
+			}
+		})
+
+		It("fails for positions outside of the layer", func() {
+			for _, position := range [][2]int{{-1, 0}, {3, 0}, {0, 2}} {
+				_, err := m.TileAt(m.Layers[0], position[0], position[1])
+				Expect(err).To(HaveOccurred())
+			}
+		})
+
+		It("keeps tile information when encoding", func() {
+			var buffer bytes.Buffer
+			Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+			Expect(buffer.String()).To(ContainSubstring(`probability="0"`))
+			decoded, err := NewMap(&buffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.Tilesets[0].Tiles).To(Equal(m.Tilesets[0].Tiles))
+
+			buffer.Reset()
+			Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+			decoded, err = NewMap(&buffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded.Tilesets[0].Tiles).To(Equal(m.Tilesets[0].Tiles))
+		})
+	})
 })
@@ -0,0 +1,345 @@
+//Package pathfinding finds paths between tiles of tmx maps
+package pathfinding
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+
+	"github.com/manyminds/tmx"
+)
+
+//Layout describes how the tiles of a map are arranged
+type Layout struct {
+	//Orientation is orthogonal, isometric, staggered or hexagonal
+	Orientation string
+	//StaggerAxis is x or y for staggered and hexagonal maps
+	StaggerAxis string
+	//StaggerIndex is odd or even for staggered and hexagonal maps
+	StaggerIndex string
+}
+
+//MapLayout returns the layout of m
+func MapLayout(m tmx.Map) Layout {
+	return Layout{
+		Orientation:  m.Orientation,
+		StaggerAxis:  m.StaggerAxis,
+		StaggerIndex: m.StaggerIndex,
+	}
+}
+
+//CornerCutting decides if diagonal moves are allowed next to blocked tiles
+type CornerCutting uint8
+
+const (
+	//NoCornerCutting allows diagonal moves only if both tiles
+	//sharing an edge with the start and the target are walkable
+	NoCornerCutting CornerCutting = iota
+	//CutOneCorner allows diagonal moves if one of these tiles is walkable
+	CutOneCorner
+	//CutCorners always allows diagonal moves
+	CutCorners
+)
+
+//Grid contains the cost of entering every tile of a map
+type Grid struct {
+	Width  int
+	Height int
+	Layout Layout
+	//Diagonal allows moves to tiles that only share a corner,
+	//there are no such tiles in hexagonal maps
+	Diagonal      bool
+	CornerCutting CornerCutting
+	//costs of entering tiles, blocked tiles cost +Inf
+	costs []float64
+}
+
+//NewGrid creates a grid of walkable tiles that cost 1
+func NewGrid(width, height int, layout Layout) *Grid {
+	costs := make([]float64, width*height)
+	for i := range costs {
+		costs[i] = 1
+	}
+
+	return &Grid{Width: width, Height: height, Layout: layout, costs: costs}
+}
+
+//contains returns true if x, y is inside of the grid
+func (g Grid) contains(x, y int) bool {
+	return x >= 0 && y >= 0 && x < g.Width && y < g.Height
+}
+
+//SetCost sets the cost of entering the tile x, y, costs
+//below 0 block the tile. Tiles outside the grid are ignored.
+func (g *Grid) SetCost(x, y int, cost float64) {
+	if !g.contains(x, y) {
+		return
+	}
+
+	if cost < 0 {
+		cost = math.Inf(1)
+	}
+
+	g.costs[y*g.Width+x] = cost
+}
+
+//SetBlocked blocks the tile x, y
+func (g *Grid) SetBlocked(x, y int) {
+	g.SetCost(x, y, -1)
+}
+
+//Cost returns the cost of entering tile x, y, ok
+//is false for blocked tiles and tiles outside the grid
+func (g Grid) Cost(x, y int) (cost float64, ok bool) {
+	if !g.contains(x, y) {
+		return 0, false
+	}
+
+	cost = g.costs[y*g.Width+x]
+	return cost, !math.IsInf(cost, 1)
+}
+
+//IsWalkable returns true if the tile x, y can be entered
+func (g Grid) IsWalkable(x, y int) bool {
+	_, ok := g.Cost(x, y)
+	return ok
+}
+
+//TileCost returns the cost of entering a tile with the given information,
+//which is nil for tiles without information. ok is false for blocked tiles.
+type TileCost func(tile *tmx.Tile) (cost float64, ok bool)
+
+//BlockedByProperty blocks all tiles whose property with the given name is true
+func BlockedByProperty(name string) TileCost {
+	return func(tile *tmx.Tile) (float64, bool) {
+		if tile == nil {
+			return 1, true
+		}
+
+		value, _ := tile.Properties.Get(name)
+		blocked, _ := strconv.ParseBool(value)
+		return 1, !blocked
+	}
+}
+
+//CostByProperty uses the float property with the given name as cost,
+//negative values block tiles and tiles without the property cost 1
+func CostByProperty(name string) TileCost {
+	return func(tile *tmx.Tile) (float64, bool) {
+		if tile == nil {
+			return 1, true
+		}
+
+		value, ok := tile.Properties.Get(name)
+		if !ok {
+			return 1, true
+		}
+
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 1, true
+		}
+
+		return cost, cost >= 0
+	}
+}
+
+//CostByClass uses the costs of the tiles' classes, negative
+//costs block tiles and tiles of other classes cost 1
+func CostByClass(costs map[string]float64) TileCost {
+	return func(tile *tmx.Tile) (float64, bool) {
+		if tile == nil {
+			return 1, true
+		}
+
+		cost, ok := costs[tile.GetClass()]
+		if !ok {
+			return 1, true
+		}
+
+		return cost, cost >= 0
+	}
+}
+
+//GridOptions configure how a grid is created from a map
+type GridOptions struct {
+	//Layers selects the tile layers that are used including those in groups,
+	//indices are those of Map.AllLayers. All layers are used if nil.
+	Layers tmx.LayerFilter
+	//Cost of the tiles, every tile of the selected layers is blocked if nil,
+	//so a collision layer can be used. Empty tiles are always walkable, a tile
+	//is blocked if it is blocked in any layer and costs the maximum otherwise.
+	Cost TileCost
+	//Diagonal allows moves to tiles that only share a corner
+	Diagonal      bool
+	CornerCutting CornerCutting
+}
+
+//NewGridFromMap creates a grid of the size and layout of m
+func NewGridFromMap(m tmx.Map, opts GridOptions) (*Grid, error) {
+	g := NewGrid(m.Width, m.Height, MapLayout(m))
+	g.Diagonal = opts.Diagonal
+	g.CornerCutting = opts.CornerCutting
+
+	//costs of tiles without any tile in the selected layers
+	empty := make([]bool, len(g.costs))
+	for i := range empty {
+		empty[i] = true
+	}
+
+	for index, layer := range m.AllLayers() {
+		l := *layer
+		if opts.Layers != nil && !opts.Layers(index, l) {
+			continue
+		}
+
+		if l.Width <= 0 && len(l.Data.DataTiles) > 0 {
+			return nil, fmt.Errorf("layer %s has tiles but no width", l.Name)
+		}
+
+		for i, dt := range l.Data.DataTiles {
+			x, y := i%l.Width, i/l.Width
+			if dt.GID == 0 || !g.contains(x, y) {
+				continue
+			}
+
+			cost, ok := math.Inf(1), false
+			if opts.Cost != nil {
+				tile, err := m.GetTileForGID(dt.GID)
+				if err != nil {
+					return nil, err
+				}
+
+				cost, ok = opts.Cost(tile)
+			}
+
+			j := y*g.Width + x
+			switch {
+			case !ok:
+				g.costs[j] = math.Inf(1)
+			case empty[j] || cost > g.costs[j]:
+				g.costs[j] = cost
+			}
+
+			empty[j] = false
+		}
+	}
+
+	return g, nil
+}
+
+//edgeNeighbours returns all tiles sharing an edge with p
+func (g Grid) edgeNeighbours(p image.Point) []image.Point {
+	var offsets []image.Point
+	switch g.Layout.Orientation {
+	case "staggered":
+		offsets = g.staggeredOffsets(p, false)
+	case "hexagonal":
+		offsets = g.staggeredOffsets(p, true)
+	default:
+		offsets = []image.Point{{X: 1}, {Y: 1}, {X: -1}, {Y: -1}}
+	}
+
+	return g.translate(p, offsets)
+}
+
+//cornerNeighbours returns all tiles that only share a corner with p
+func (g Grid) cornerNeighbours(p image.Point) []image.Point {
+	var offsets []image.Point
+	switch g.Layout.Orientation {
+	case "hexagonal":
+		return nil
+	case "staggered":
+		if g.Layout.StaggerAxis == "x" {
+			offsets = []image.Point{{X: 2}, {Y: 1}, {X: -2}, {Y: -1}}
+		} else {
+			offsets = []image.Point{{X: 1}, {Y: 2}, {X: -1}, {Y: -2}}
+		}
+	default:
+		offsets = []image.Point{{X: 1, Y: 1}, {X: -1, Y: 1}, {X: -1, Y: -1}, {X: 1, Y: -1}}
+	}
+
+	return g.translate(p, offsets)
+}
+
+//translate returns p moved by all offsets
+func (g Grid) translate(p image.Point, offsets []image.Point) []image.Point {
+	result := make([]image.Point, len(offsets))
+	for i, offset := range offsets {
+		result[i] = p.Add(offset)
+	}
+
+	return result
+}
+
+//isShifted returns true if the row or column of p along
+//the stagger axis is shifted by half a tile
+func (g Grid) isShifted(p image.Point) bool {
+	index := p.Y
+	if g.Layout.StaggerAxis == "x" {
+		index = p.X
+	}
+
+	odd := index%2 != 0
+	if g.Layout.StaggerIndex == "even" {
+		return !odd
+	}
+
+	return odd
+}
+
+//staggeredOffsets returns the offsets of the tiles sharing an edge
+//with p in staggered maps, hexagons have two additional neighbours
+func (g Grid) staggeredOffsets(p image.Point, hexagonal bool) []image.Point {
+	//the neighbours in the adjacent rows or columns are
+	//either at the same index and the next or the previous
+	other := -1
+	if g.isShifted(p) {
+		other = 1
+	}
+
+	if g.Layout.StaggerAxis == "x" {
+		offsets := []image.Point{{X: 1}, {X: 1, Y: other}, {X: -1}, {X: -1, Y: other}}
+		if hexagonal {
+			offsets = append(offsets, image.Point{Y: 1}, image.Point{Y: -1})
+		}
+
+		return offsets
+	}
+
+	offsets := []image.Point{{Y: 1}, {X: other, Y: 1}, {Y: -1}, {X: other, Y: -1}}
+	if hexagonal {
+		offsets = append(offsets, image.Point{X: 1}, image.Point{X: -1})
+	}
+
+	return offsets
+}
+
+//center returns the center of p in a plane where
+//the centers of tiles sharing an edge have distance 1
+func (g Grid) center(p image.Point) (float64, float64) {
+	x, y := float64(p.X), float64(p.Y)
+	shift := 0.0
+	if g.isShifted(p) {
+		shift = 0.5
+	}
+
+	switch g.Layout.Orientation {
+	case "staggered":
+		//neighbours are half a tile away in both directions
+		if g.Layout.StaggerAxis == "x" {
+			return x / 2 * math.Sqrt2, (y + shift) * math.Sqrt2
+		}
+
+		return (x + shift) * math.Sqrt2, y / 2 * math.Sqrt2
+	case "hexagonal":
+		if g.Layout.StaggerAxis == "x" {
+			return x * math.Sqrt(3) / 2, y + shift
+		}
+
+		return x + shift, y * math.Sqrt(3) / 2
+	default:
+		return x, y
+	}
+}
@@ -0,0 +1,106 @@
+package pathfinding_test
+
+import (
+	"os"
+
+	"github.com/manyminds/tmx"
+	. "github.com/manyminds/tmx/pathfinding"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func loadMap(name string) tmx.Map {
+	f, err := os.Open("../testfiles/" + name)
+	Expect(err).ToNot(HaveOccurred())
+	defer f.Close()
+	m, err := tmx.NewMap(f)
+	Expect(err).ToNot(HaveOccurred())
+	return *m
+}
+
+func costAt(g *Grid, x, y int) float64 {
+	cost, ok := g.Cost(x, y)
+	Expect(ok).To(BeTrue())
+	return cost
+}
+
+var _ = Describe("Test creating grids", func() {
+	var m tmx.Map
+
+	BeforeEach(func() {
+		m = loadMap("pathfinding.tmx")
+	})
+
+	It("blocks tiles by property", func() {
+		g, err := NewGridFromMap(m, GridOptions{Layers: tmx.LayersByName("Ground"), Cost: BlockedByProperty("blocked")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.Width).To(Equal(5))
+		Expect(g.Height).To(Equal(4))
+		Expect(g.Layout).To(Equal(Layout{Orientation: "orthogonal"}))
+
+		for x := 1; x < 4; x++ {
+			Expect(g.IsWalkable(x, 1)).To(BeFalse())
+		}
+		Expect(g.IsWalkable(0, 1)).To(BeTrue())
+		Expect(costAt(g, 2, 2)).To(Equal(1.0))
+	})
+
+	It("uses property values as costs", func() {
+		g, err := NewGridFromMap(m, GridOptions{Layers: tmx.LayersByName("Ground"), Cost: CostByProperty("cost")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(costAt(g, 2, 2)).To(Equal(3.0))
+		Expect(costAt(g, 2, 1)).To(Equal(1.0))
+	})
+
+	It("uses the costs of tile classes in all layers", func() {
+		g, err := NewGridFromMap(m, GridOptions{Cost: CostByClass(map[string]float64{"wall": -1, "swamp": 3})})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.IsWalkable(2, 1)).To(BeFalse())
+		Expect(g.IsWalkable(0, 2)).To(BeFalse())
+		Expect(costAt(g, 2, 2)).To(Equal(3.0))
+		Expect(costAt(g, 4, 3)).To(Equal(1.0))
+	})
+
+	It("blocks all tiles of collision layers", func() {
+		g, err := NewGridFromMap(m, GridOptions{Layers: tmx.LayersByName("Collision")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.IsWalkable(0, 1)).To(BeFalse())
+		Expect(g.IsWalkable(0, 2)).To(BeFalse())
+		Expect(g.IsWalkable(2, 1)).To(BeTrue())
+	})
+
+	It("blocks all tiles of collision layers in groups", func() {
+		m.Groups = []tmx.Group{{Name: "Physics", Layers: []tmx.Layer{m.Layers[1]}}}
+		m.Layers = m.Layers[:1]
+
+		g, err := NewGridFromMap(m, GridOptions{Layers: tmx.LayersByName("Collision")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.IsWalkable(0, 1)).To(BeFalse())
+		Expect(g.IsWalkable(0, 2)).To(BeFalse())
+		Expect(g.IsWalkable(2, 1)).To(BeTrue())
+	})
+
+	It("fails for layers with tiles but without width", func() {
+		m.Layers[0].Width = 0
+		_, err := NewGridFromMap(m, GridOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("changes costs of single tiles", func() {
+		g := NewGrid(2, 2, Layout{})
+		g.SetCost(1, 0, 2.5)
+		g.SetBlocked(0, 1)
+		g.SetCost(5, 5, 1)
+
+		Expect(costAt(g, 1, 0)).To(Equal(2.5))
+		Expect(g.IsWalkable(0, 1)).To(BeFalse())
+		Expect(g.IsWalkable(1, 1)).To(BeTrue())
+		Expect(g.IsWalkable(-1, 0)).To(BeFalse())
+	})
+
+	It("reads the layout of staggered maps", func() {
+		m := loadMap("hexagonal.tmx")
+		Expect(m.HexSideLength).To(Equal(14))
+		Expect(MapLayout(m)).To(Equal(Layout{Orientation: "hexagonal", StaggerAxis: "y", StaggerIndex: "even"}))
+	})
+})
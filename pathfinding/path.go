@@ -0,0 +1,182 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+//ErrNoPath is returned if the target can't be reached
+var ErrNoPath = errors.New("no path found")
+
+//step is a move to a neighbouring tile
+type step struct {
+	to   image.Point
+	cost float64
+}
+
+//neighbours returns all walkable tiles that can be reached from p in one step
+func (g Grid) neighbours(p image.Point) []step {
+	var steps []step
+	edges := g.edgeNeighbours(p)
+	for _, n := range edges {
+		if cost, ok := g.Cost(n.X, n.Y); ok {
+			steps = append(steps, step{to: n, cost: cost})
+		}
+	}
+
+	if !g.Diagonal {
+		return steps
+	}
+
+	for _, n := range g.cornerNeighbours(p) {
+		cost, ok := g.Cost(n.X, n.Y)
+		if !ok || !g.canCut(edges, n) {
+			continue
+		}
+
+		steps = append(steps, step{to: n, cost: cost * math.Sqrt2})
+	}
+
+	return steps
+}
+
+//canCut returns true if a diagonal move to n is allowed, edges are the tiles
+//sharing an edge with the start. The tiles sharing an edge with both the start
+//and n are the corners that are cut.
+func (g Grid) canCut(edges []image.Point, n image.Point) bool {
+	if g.CornerCutting == CutCorners {
+		return true
+	}
+
+	walkable, blocked := 0, 0
+	for _, corner := range g.edgeNeighbours(n) {
+		for _, edge := range edges {
+			if corner != edge {
+				continue
+			}
+
+			if g.IsWalkable(corner.X, corner.Y) {
+				walkable++
+			} else {
+				blocked++
+			}
+		}
+	}
+
+	if g.CornerCutting == CutOneCorner {
+		return walkable > 0
+	}
+
+	return blocked == 0
+}
+
+//minCost returns the lowest cost of all walkable tiles
+func (g Grid) minCost() float64 {
+	min := math.Inf(1)
+	for _, cost := range g.costs {
+		min = math.Min(min, cost)
+	}
+
+	return min
+}
+
+//FindPath returns the cheapest path from one tile to another using A*.
+//The path contains both tiles, the cost of a path is the sum of the costs
+//of the entered tiles, diagonal moves cost √2 times as much. ErrNoPath is
+//returned if there is no path.
+func (g Grid) FindPath(from, to image.Point) ([]image.Point, float64, error) {
+	if !g.IsWalkable(from.X, from.Y) {
+		return nil, 0, fmt.Errorf("start %d,%d is not walkable", from.X, from.Y)
+	}
+
+	if !g.IsWalkable(to.X, to.Y) {
+		return nil, 0, fmt.Errorf("target %d,%d is not walkable", to.X, to.Y)
+	}
+
+	minCost := g.minCost()
+	targetX, targetY := g.center(to)
+	estimate := func(p image.Point) float64 {
+		x, y := g.center(p)
+		return math.Hypot(targetX-x, targetY-y) * minCost
+	}
+
+	costs := map[image.Point]float64{from: 0}
+	previous := map[image.Point]image.Point{}
+	open := &queue{}
+	heap.Push(open, &node{point: from, estimate: estimate(from)})
+
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*node)
+		if current.point == to {
+			return path(previous, from, to), current.cost, nil
+		}
+
+		if current.cost > costs[current.point] {
+			continue
+		}
+
+		for _, s := range g.neighbours(current.point) {
+			cost := current.cost + s.cost
+			if known, ok := costs[s.to]; ok && known <= cost {
+				continue
+			}
+
+			costs[s.to] = cost
+			previous[s.to] = current.point
+			heap.Push(open, &node{point: s.to, cost: cost, estimate: cost + estimate(s.to)})
+		}
+	}
+
+	return nil, 0, ErrNoPath
+}
+
+//path follows previous back from to
+func path(previous map[image.Point]image.Point, from, to image.Point) []image.Point {
+	result := []image.Point{to}
+	for p := to; p != from; {
+		p = previous[p]
+		result = append(result, p)
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result
+}
+
+//node is a tile in the open set
+type node struct {
+	point    image.Point
+	cost     float64
+	estimate float64
+}
+
+//queue is a priority queue of nodes ordered by their estimate
+type queue []*node
+
+func (q queue) Len() int {
+	return len(q)
+}
+
+func (q queue) Less(i, j int) bool {
+	return q[i].estimate < q[j].estimate
+}
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *queue) Push(x interface{}) {
+	*q = append(*q, x.(*node))
+}
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
@@ -0,0 +1,144 @@
+package pathfinding_test
+
+import (
+	"image"
+	"math"
+
+	"github.com/manyminds/tmx"
+	. "github.com/manyminds/tmx/pathfinding"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test finding paths", func() {
+	classes := CostByClass(map[string]float64{"wall": -1, "swamp": 3})
+
+	grid := func(opts GridOptions) *Grid {
+		opts.Cost = classes
+		g, err := NewGridFromMap(loadMap("pathfinding.tmx"), opts)
+		Expect(err).ToNot(HaveOccurred())
+		return g
+	}
+
+	It("finds the cheapest path around blocked tiles", func() {
+		path, cost, err := grid(GridOptions{}).FindPath(image.Pt(2, 0), image.Pt(2, 2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(8.0))
+		Expect(path).To(Equal([]image.Point{
+			{2, 0}, {3, 0}, {4, 0}, {4, 1}, {4, 2}, {3, 2}, {2, 2},
+		}))
+	})
+
+	It("avoids expensive tiles", func() {
+		g := grid(GridOptions{})
+		g.SetCost(2, 2, 10)
+		path, cost, err := g.FindPath(image.Pt(1, 2), image.Pt(3, 2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(4.0))
+		Expect(path).To(HaveLen(5))
+		Expect(path).ToNot(ContainElement(image.Pt(2, 2)))
+	})
+
+	It("moves diagonally with corner cutting rules", func() {
+		for _, c := range []struct {
+			cutting CornerCutting
+			cost    float64
+		}{
+			{NoCornerCutting, 8},
+			{CutOneCorner, 4 + 2*math.Sqrt2},
+			{CutCorners, 4 + 2*math.Sqrt2},
+		} {
+			_, cost, err := grid(GridOptions{Diagonal: true, CornerCutting: c.cutting}).FindPath(image.Pt(2, 0), image.Pt(2, 2))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cost).To(BeNumerically("~", c.cost, 1e-9), c.cutting)
+		}
+
+		g := NewGrid(2, 2, Layout{})
+		g.Diagonal = true
+		g.SetBlocked(1, 0)
+		g.SetBlocked(0, 1)
+		_, _, err := g.FindPath(image.Pt(0, 0), image.Pt(1, 1))
+		Expect(err).To(Equal(ErrNoPath))
+		g.CornerCutting = CutOneCorner
+		_, _, err = g.FindPath(image.Pt(0, 0), image.Pt(1, 1))
+		Expect(err).To(Equal(ErrNoPath))
+		g.CornerCutting = CutCorners
+		path, _, err := g.FindPath(image.Pt(0, 0), image.Pt(1, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal([]image.Point{{0, 0}, {1, 1}}))
+	})
+
+	It("fails for unreachable and blocked tiles", func() {
+		g := grid(GridOptions{})
+		g.SetBlocked(1, 3)
+		g.SetBlocked(3, 3)
+		g.SetBlocked(2, 2)
+		_, _, err := g.FindPath(image.Pt(0, 0), image.Pt(2, 3))
+		Expect(err).To(Equal(ErrNoPath))
+
+		_, _, err = g.FindPath(image.Pt(0, 1), image.Pt(2, 3))
+		Expect(err).To(HaveOccurred())
+		_, _, err = g.FindPath(image.Pt(0, 0), image.Pt(9, 9))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns paths to the start", func() {
+		path, cost, err := grid(GridOptions{}).FindPath(image.Pt(4, 3), image.Pt(4, 3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(BeZero())
+		Expect(path).To(Equal([]image.Point{{4, 3}}))
+	})
+
+	It("uses the neighbours of staggered maps", func() {
+		g := NewGrid(3, 3, Layout{Orientation: "staggered", StaggerAxis: "y", StaggerIndex: "odd"})
+		path, cost, err := g.FindPath(image.Pt(0, 1), image.Pt(1, 0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(1.0))
+		Expect(path).To(HaveLen(2))
+
+		_, cost, err = g.FindPath(image.Pt(0, 1), image.Pt(1, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(2.0))
+
+		g.Diagonal = true
+		_, cost, err = g.FindPath(image.Pt(0, 1), image.Pt(1, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(math.Sqrt2))
+
+		g = NewGrid(3, 3, Layout{Orientation: "staggered", StaggerAxis: "x", StaggerIndex: "even"})
+		_, cost, err = g.FindPath(image.Pt(0, 0), image.Pt(1, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(1.0))
+	})
+
+	It("uses the neighbours of hexagonal maps", func() {
+		m := loadMap("hexagonal.tmx")
+		g, err := NewGridFromMap(m, GridOptions{Cost: CostByClass(map[string]float64{}), Diagonal: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, to := range []image.Point{{1, 0}, {0, 1}, {1, 1}} {
+			_, cost, err := g.FindPath(image.Pt(0, 0), to)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cost).To(Equal(1.0), "%v", to)
+		}
+
+		_, cost, err := g.FindPath(image.Pt(0, 1), image.Pt(2, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(2.0))
+
+		g.SetBlocked(1, 1)
+		path, cost, err := g.FindPath(image.Pt(0, 1), image.Pt(2, 1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(3.0))
+		Expect(path).To(HaveLen(4))
+	})
+
+	It("uses layers of the map", func() {
+		m := loadMap("pathfinding.tmx")
+		g, err := NewGridFromMap(m, GridOptions{Layers: tmx.LayersByName("Collision")})
+		Expect(err).ToNot(HaveOccurred())
+		_, cost, err := g.FindPath(image.Pt(0, 0), image.Pt(0, 3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cost).To(Equal(5.0))
+	})
+})
@@ -0,0 +1,13 @@
+package pathfinding_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPathfinding(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pathfinding Suite")
+}
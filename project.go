@@ -0,0 +1,325 @@
+package tmx
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+//Project contains the custom property types of a .tiled-project file,
+//set it in LoadOptions to resolve the properties of loaded maps
+type Project struct {
+	PropertyTypes []PropertyType `json:"propertyTypes"`
+}
+
+//PropertyType is a custom enum or class defined in a project
+type PropertyType struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	//Type is either enum or class
+	Type string `json:"type"`
+	//StorageType of enums is either string or int
+	StorageType string `json:"storageType"`
+	//Values of enums, int values are indices of this list
+	Values []string `json:"values"`
+	//ValuesAsFlags allows multiple values of an enum,
+	//for int storage every value is one bit
+	ValuesAsFlags bool `json:"valuesAsFlags"`
+	//Members of classes contain the default values
+	Members Properties `json:"members"`
+	//UseAs lists where a class can be used, e.g. map, layer or object
+	UseAs []string `json:"useAs"`
+}
+
+//NewProject loads a project from the given io.Reader
+func NewProject(r io.Reader) (*Project, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var project Project
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+//GetPropertyType returns the custom type with the
+//given name, nil is returned for unknown types
+func (p *Project) GetPropertyType(name string) *PropertyType {
+	if name == "" {
+		return nil
+	}
+
+	for i := range p.PropertyTypes {
+		if p.PropertyTypes[i].Name == name {
+			return &p.PropertyTypes[i]
+		}
+	}
+
+	return nil
+}
+
+//ResolveProperties returns props of an element with the given class.
+//Members of the class that are not set are added with their default
+//values, class properties are resolved recursively and enum values
+//stored as int are replaced by the names of their values. The type of
+//enums is kept, EncodeOptions.Project converts them back when writing.
+func (p *Project) ResolveProperties(class string, props Properties) Properties {
+	return p.resolveProperties(class, props, map[string]bool{})
+}
+
+//resolveProperties resolves props, visited contains
+//all classes that are currently resolved
+func (p *Project) resolveProperties(class string, props Properties, visited map[string]bool) Properties {
+	var members Properties
+	if t := p.GetPropertyType(class); t != nil && t.Type == "class" && !visited[class] {
+		members = t.Members
+		visited[class] = true
+		defer delete(visited, class)
+	}
+
+	var result Properties
+	for _, member := range members {
+		property := member
+		for _, own := range props {
+			if own.Name == member.Name {
+				property = own
+				property.Type = member.Type
+				property.PropertyType = member.PropertyType
+				break
+			}
+		}
+
+		result = append(result, p.resolveProperty(property, visited))
+	}
+
+	for _, own := range props {
+		isMember := false
+		for _, member := range members {
+			if own.Name == member.Name {
+				isMember = true
+				break
+			}
+		}
+
+		if !isMember {
+			result = append(result, p.resolveProperty(own, visited))
+		}
+	}
+
+	return result
+}
+
+//resolveProperty resolves the members of class
+//properties and the values of enums
+func (p *Project) resolveProperty(property Property, visited map[string]bool) Property {
+	t := p.GetPropertyType(property.PropertyType)
+	if t == nil {
+		return property
+	}
+
+	switch t.Type {
+	case "class":
+		property.Type = "class"
+		property.Properties = p.resolveProperties(t.Name, property.Properties, visited)
+	case "enum":
+		if value, ok := t.enumValue(property.Value); ok {
+			property.Value = value
+		}
+	}
+
+	return property
+}
+
+//enumValue returns the names of an enum value stored as int,
+//multiple names of flags are separated by comma
+func (t PropertyType) enumValue(value string) (string, bool) {
+	if t.StorageType != "int" {
+		return "", false
+	}
+
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 {
+		return "", false
+	}
+
+	if !t.ValuesAsFlags {
+		if index >= len(t.Values) {
+			return "", false
+		}
+
+		return t.Values[index], true
+	}
+
+	var names []string
+	for i, name := range t.Values {
+		if index&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(names, ","), true
+}
+
+//resolveMap resolves the properties of m and all of its
+//tilesets, tiles, wang sets, layers, object groups and objects
+func (p *Project) resolveMap(m *Map) {
+	m.Properties = p.ResolveProperties(m.Class, m.Properties)
+
+	for i := range m.Tilesets {
+		tileset := &m.Tilesets[i]
+		tileset.Properties = p.ResolveProperties(tileset.Class, tileset.Properties)
+		for j := range tileset.Tiles {
+			tileset.Tiles[j].Properties = p.ResolveProperties(tileset.Tiles[j].GetClass(), tileset.Tiles[j].Properties)
+		}
+
+		for j := range tileset.WangSets {
+			set := &tileset.WangSets[j]
+			set.Properties = p.ResolveProperties(set.Class, set.Properties)
+			for k := range set.Colors {
+				set.Colors[k].Properties = p.ResolveProperties(set.Colors[k].Class, set.Colors[k].Properties)
+			}
+		}
+	}
+
+	m.forEachLayer(func(l *Layer) error {
+		l.Properties = p.ResolveProperties(l.Class, l.Properties)
+		return nil
+	})
+
+	m.forEachObjectGroup(func(group *ObjectGroup) error {
+		group.Properties = p.ResolveProperties(group.Class, group.Properties)
+		for j := range group.Objects {
+			//the type of an object is its class
+			group.Objects[j].Properties = p.ResolveProperties(group.Objects[j].Type, group.Objects[j].Properties)
+		}
+
+		return nil
+	})
+
+	forEachGroup(m.Groups, func(g *Group) error {
+		g.Properties = p.ResolveProperties(g.Class, g.Properties)
+		return nil
+	})
+}
+
+//storeProperties is the inverse of ResolveProperties for enums,
+//names of enum values stored as int are replaced by their values
+func (p *Project) storeProperties(props Properties) Properties {
+	if props == nil {
+		return nil
+	}
+
+	result := make(Properties, len(props))
+	for i, property := range props {
+		if t := p.GetPropertyType(property.PropertyType); t != nil && t.Type == "enum" {
+			if value, ok := t.storedValue(property.Value); ok {
+				property.Value = value
+			}
+		}
+
+		property.Properties = p.storeProperties(property.Properties)
+		result[i] = property
+	}
+
+	return result
+}
+
+//storedValue returns the int value of the comma separated
+//names of an enum stored as int
+func (t PropertyType) storedValue(names string) (string, bool) {
+	if t.StorageType != "int" {
+		return "", false
+	}
+
+	if _, err := strconv.Atoi(names); err == nil {
+		return names, true
+	}
+
+	value := 0
+	for _, name := range strings.Split(names, ",") {
+		index := -1
+		for i, v := range t.Values {
+			if v == name {
+				index = i
+				break
+			}
+		}
+
+		switch {
+		case names == "" && t.ValuesAsFlags:
+		case index < 0:
+			return "", false
+		case t.ValuesAsFlags:
+			value |= 1 << uint(index)
+		default:
+			value = index
+		}
+	}
+
+	return strconv.Itoa(value), true
+}
+
+//storeMap returns a copy of m whose enums are stored as int, all
+//properties are copied so m is not changed
+func (p *Project) storeMap(m Map) Map {
+	m.Properties = p.storeProperties(m.Properties)
+
+	m.Tilesets = append([]Tileset(nil), m.Tilesets...)
+	for i := range m.Tilesets {
+		tileset := &m.Tilesets[i]
+		tileset.Properties = p.storeProperties(tileset.Properties)
+		tileset.Tiles = append([]Tile(nil), tileset.Tiles...)
+		for j := range tileset.Tiles {
+			tileset.Tiles[j].Properties = p.storeProperties(tileset.Tiles[j].Properties)
+		}
+
+		tileset.WangSets = append([]WangSet(nil), tileset.WangSets...)
+		for j := range tileset.WangSets {
+			set := &tileset.WangSets[j]
+			set.Properties = p.storeProperties(set.Properties)
+			set.Colors = append([]WangColor(nil), set.Colors...)
+			for k := range set.Colors {
+				set.Colors[k].Properties = p.storeProperties(set.Colors[k].Properties)
+			}
+		}
+	}
+
+	root := p.storeGroup(Group{Layers: m.Layers, ObjectGroups: m.ObjectGroups, Groups: m.Groups})
+	m.Layers = root.Layers
+	m.ObjectGroups = root.ObjectGroups
+	m.Groups = root.Groups
+	return m
+}
+
+//storeGroup returns a copy of g whose enums are stored as int
+func (p *Project) storeGroup(g Group) Group {
+	g.Properties = p.storeProperties(g.Properties)
+
+	g.Layers = append([]Layer(nil), g.Layers...)
+	for i := range g.Layers {
+		g.Layers[i].Properties = p.storeProperties(g.Layers[i].Properties)
+	}
+
+	g.ObjectGroups = append([]ObjectGroup(nil), g.ObjectGroups...)
+	for i := range g.ObjectGroups {
+		group := &g.ObjectGroups[i]
+		group.Properties = p.storeProperties(group.Properties)
+		group.Objects = append([]Object(nil), group.Objects...)
+		for j := range group.Objects {
+			group.Objects[j].Properties = p.storeProperties(group.Objects[j].Properties)
+		}
+	}
+
+	g.Groups = append([]Group(nil), g.Groups...)
+	for i := range g.Groups {
+		g.Groups[i] = p.storeGroup(g.Groups[i])
+	}
+
+	return g
+}
@@ -0,0 +1,163 @@
+package tmx_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test project custom types", func() {
+	var project *Project
+
+	load := func(opts LoadOptions) *Map {
+		m, err := loadTestMapWithOptions("testfiles/project/custom_types.tmx", opts)
+		Expect(err).ToNot(HaveOccurred())
+		return m
+	}
+
+	BeforeEach(func() {
+		f, err := os.Open("testfiles/project/example.tiled-project")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		project, err = NewProject(f)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("loads custom types", func() {
+		Expect(project.PropertyTypes).To(HaveLen(6))
+		direction := project.GetPropertyType("Direction")
+		Expect(direction).ToNot(BeNil())
+		Expect(direction.Type).To(Equal("enum"))
+		Expect(direction.StorageType).To(Equal("int"))
+		Expect(direction.Values).To(Equal([]string{"North", "East", "South", "West"}))
+
+		monster := project.GetPropertyType("Monster")
+		Expect(monster).ToNot(BeNil())
+		Expect(monster.UseAs).To(ConsistOf("property", "object"))
+		Expect(monster.Members).To(Equal(Properties{
+			{Name: "name", Value: "Orc"},
+			{Name: "facing", Type: "int", PropertyType: "Direction", Value: "0"},
+			{Name: "stats", Type: "class", PropertyType: "Stats"},
+			{Name: "terrain", PropertyType: "Terrain", Value: "Solid"},
+		}))
+
+		Expect(project.GetPropertyType("Unknown")).To(BeNil())
+	})
+
+	It("keeps typed properties without project", func() {
+		m := load(LoadOptions{})
+		Expect(m.Class).To(Equal("Level"))
+		Expect(m.Tilesets[0].Class).To(Equal("Level"))
+		Expect(m.Layers[0].Class).To(Equal("Level"))
+		Expect(m.Properties).To(Equal(Properties{
+			{Name: "dark", Type: "bool", Value: "true"},
+			{Name: "author", Value: "manyminds"},
+		}))
+		Expect(m.ObjectGroups[0].Objects[0].Properties).To(Equal(Properties{
+			{Name: "facing", Type: "int", PropertyType: "Direction", Value: "2"},
+			{Name: "movement", Type: "int", PropertyType: "Movement", Value: "5"},
+			{Name: "stats", Type: "class", PropertyType: "Stats", Properties: Properties{
+				{Name: "hp", Type: "int", Value: "20"},
+			}},
+		}))
+		Expect(m.ObjectGroups[0].Objects[1].Properties).To(BeEmpty())
+	})
+
+	It("applies defaults and enum values", func() {
+		m := load(LoadOptions{Project: project})
+		Expect(m.Properties).To(Equal(Properties{
+			{Name: "music", Type: "file", Value: "theme.ogg"},
+			{Name: "dark", Type: "bool", Value: "true"},
+			{Name: "author", Value: "manyminds"},
+		}))
+
+		defaults := Properties{
+			{Name: "music", Type: "file", Value: "theme.ogg"},
+			{Name: "dark", Type: "bool", Value: "false"},
+		}
+		Expect(m.Tilesets[0].Properties).To(Equal(defaults))
+		Expect(m.Layers[0].Properties).To(Equal(defaults))
+		Expect(m.ObjectGroups[0].Properties).To(BeEmpty())
+
+		Expect(m.ObjectGroups[0].Objects[0].Properties).To(Equal(Properties{
+			{Name: "name", Value: "Orc"},
+			{Name: "facing", Type: "int", PropertyType: "Direction", Value: "South"},
+			{Name: "stats", Type: "class", PropertyType: "Stats", Properties: Properties{
+				{Name: "hp", Type: "int", Value: "20"},
+				{Name: "speed", Type: "float", Value: "1.5"},
+			}},
+			{Name: "terrain", PropertyType: "Terrain", Value: "Solid"},
+			{Name: "movement", Type: "int", PropertyType: "Movement", Value: "Ground,Sea"},
+		}))
+
+		Expect(m.ObjectGroups[0].Objects[1].Properties).To(Equal(Properties{
+			{Name: "name", Value: "Orc"},
+			{Name: "facing", Type: "int", PropertyType: "Direction", Value: "North"},
+			{Name: "stats", Type: "class", PropertyType: "Stats", Properties: Properties{
+				{Name: "hp", Type: "int", Value: "10"},
+				{Name: "speed", Type: "float", Value: "1.5"},
+			}},
+			{Name: "terrain", PropertyType: "Terrain", Value: "Solid"},
+		}))
+	})
+
+	It("writes typed properties", func() {
+		m := load(LoadOptions{})
+		expected := m.ObjectGroups[0].Objects[0].Properties
+
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`<property name="facing" type="int" propertytype="Direction" value="2"></property>`))
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Class).To(Equal("Level"))
+		Expect(decoded.ObjectGroups[0].Objects[0].Properties).To(Equal(expected))
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`"value": {`))
+		decoded, err = NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Class).To(Equal("Level"))
+		Expect(decoded.Layers[0].Class).To(Equal("Level"))
+		Expect(decoded.ObjectGroups[0].Objects[0].Properties).To(Equal(expected))
+	})
+
+	It("writes enums of resolved maps as int", func() {
+		m := load(LoadOptions{Project: project})
+		expected := m.ObjectGroups[0].Objects[0].Properties
+
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{Project: project})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`<property name="facing" type="int" propertytype="Direction" value="2"></property>`))
+		Expect(buffer.String()).To(ContainSubstring(`<property name="movement" type="int" propertytype="Movement" value="5"></property>`))
+		Expect(m.ObjectGroups[0].Objects[0].Properties).To(Equal(expected))
+		decoded, err := NewMapWithOptions(&buffer, LoadOptions{Project: project})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.ObjectGroups[0].Objects[0].Properties).To(Equal(expected))
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{Project: project})).To(Succeed())
+		decoded, err = NewMapWithOptions(&buffer, LoadOptions{Project: project})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.ObjectGroups[0].Objects[0].Properties).To(Equal(expected))
+
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).ToNot(Succeed())
+	})
+
+	It("does not resolve recursive classes forever", func() {
+		recursive, err := NewProject(strings.NewReader(`{"propertyTypes": [
+			{"name": "Node", "type": "class", "members": [
+				{"name": "next", "type": "class", "propertyType": "Node", "value": {}}
+			]}
+		]}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recursive.ResolveProperties("Node", nil)).To(Equal(Properties{
+			{Name: "next", Type: "class", PropertyType: "Node"},
+		}))
+	})
+})
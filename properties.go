@@ -0,0 +1,43 @@
+package tmx
+
+import "encoding/xml"
+
+//Properties is a list of custom properties
+//of a map, tileset, layer, object group or object
+type Properties []Property
+
+//propertiesXML is used to read and write the property elements
+type propertiesXML struct {
+	Properties []Property `xml:"property"`
+}
+
+//Get returns the value of the property with the given name
+func (p Properties) Get(name string) (string, bool) {
+	for _, property := range p {
+		if property.Name == name {
+			return property.Value, true
+		}
+	}
+
+	return "", false
+}
+
+//UnmarshalXML appends all property elements
+func (p *Properties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var decoded propertiesXML
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+
+	*p = append(*p, decoded.Properties...)
+	return nil
+}
+
+//MarshalXML writes all properties, empty properties are omitted
+func (p Properties) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	return e.EncodeElement(propertiesXML{Properties: p}, start)
+}
@@ -0,0 +1,123 @@
+package tmx
+
+//TilesetUsage contains the tiles of a tileset used by a map
+type TilesetUsage struct {
+	//Tiles counts the references of every used tile id
+	Tiles map[uint32]int
+}
+
+//IsUsed returns true if any tile of the tileset is used
+func (u TilesetUsage) IsUsed() bool {
+	return len(u.Tiles) > 0
+}
+
+//TilesetUsage returns the usage of every tileset of the map in the order
+//of Tilesets, tiles of all layers and tile objects are counted including
+//those in groups. An error is returned for gids that don't belong to any
+//tileset.
+func (m Map) TilesetUsage() ([]TilesetUsage, error) {
+	usage := make([]TilesetUsage, len(m.Tilesets))
+	for i := range usage {
+		usage[i].Tiles = map[uint32]int{}
+	}
+
+	count := func(gid GID) error {
+		tileset, err := m.GetTilesetForGID(gid &^ GIDFlips)
+		if err != nil || tileset == nil {
+			return err
+		}
+
+		for i := range m.Tilesets {
+			if &m.Tilesets[i] == tileset {
+				usage[i].Tiles[uint32(gid&^GIDFlips-tileset.FirstGID)]++
+			}
+		}
+
+		return nil
+	}
+
+	if err := m.forEachLayer(func(l *Layer) error {
+		for _, tile := range l.Data.DataTiles {
+			if err := count(tile.GID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := m.forEachObjectGroup(func(g *ObjectGroup) error {
+		for _, o := range g.Objects {
+			if err := count(GID(o.GID)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+//PruneTilesets removes all tilesets that are not used by any layer or tile
+//object and assigns FirstGIDs without gaps in the order of Tilesets. The gids
+//of all layers and objects are rewritten and the index is rebuilt. The number
+//of removed tilesets is returned, the map is not changed for invalid gids.
+func (m *Map) PruneTilesets() (int, error) {
+	usage, err := m.TilesetUsage()
+	if err != nil {
+		return 0, err
+	}
+
+	old := *m
+	var tilesets []Tileset
+	firstGIDs := map[*Tileset]GID{}
+	next := GID(1)
+	for i, tileset := range m.Tilesets {
+		if !usage[i].IsUsed() {
+			continue
+		}
+
+		firstGIDs[&m.Tilesets[i]] = next
+		tileset.FirstGID = next
+		next += GID(tileset.GetNumTiles())
+		tilesets = append(tilesets, tileset)
+	}
+
+	remap := func(gid GID) GID {
+		tileset, _ := old.GetTilesetForGID(gid &^ GIDFlips)
+		if tileset == nil {
+			return gid
+		}
+
+		return gid&GIDFlips | (gid&^GIDFlips - tileset.FirstGID + firstGIDs[tileset])
+	}
+
+	m.forEachLayer(func(l *Layer) error {
+		tiles := l.Data.DataTiles
+		for j := range tiles {
+			tiles[j].GID = remap(tiles[j].GID)
+		}
+
+		return nil
+	})
+
+	m.forEachObjectGroup(func(g *ObjectGroup) error {
+		objects := g.Objects
+		for j := range objects {
+			objects[j].GID = int(remap(GID(objects[j].GID)))
+		}
+
+		return nil
+	})
+
+	removed := len(m.Tilesets) - len(tilesets)
+	m.Tilesets = tilesets
+	m.BuildIndex()
+
+	return removed, nil
+}
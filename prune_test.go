@@ -0,0 +1,104 @@
+package tmx_test
+
+import (
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test pruning tilesets", func() {
+	var m Map
+
+	BeforeEach(func() {
+		tileset := func(name string, firstGID GID) Tileset {
+			return Tileset{FirstGID: firstGID, Name: name, TileWidth: 16, TileHeight: 16, Image: Image{Source: name + ".png", Width: 32, Height: 32}}
+		}
+
+		m = Map{Width: 3, Height: 1, TileWidth: 16, TileHeight: 16, Tilesets: []Tileset{
+			tileset("floor", 1),
+			tileset("unused", 5),
+			tileset("props", 20),
+		}}
+		m.BuildIndex()
+
+		layer := m.AddLayer("Ground")
+		Expect(layer.SetTile(0, 0, 1, 0)).To(Succeed())
+		Expect(layer.SetTile(1, 0, 1, GIDHorizontalFlip)).To(Succeed())
+		Expect(layer.SetTile(2, 0, 22, 0)).To(Succeed())
+		m.ObjectGroups = []ObjectGroup{{Objects: []Object{
+			{ID: 1, GID: 21 | GIDVerticalFlip},
+			{ID: 2},
+		}}}
+	})
+
+	It("counts the used tiles of every tileset", func() {
+		usage, err := m.TilesetUsage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage).To(HaveLen(3))
+		Expect(usage[0].Tiles).To(Equal(map[uint32]int{0: 2}))
+		Expect(usage[1].IsUsed()).To(BeFalse())
+		Expect(usage[2].Tiles).To(Equal(map[uint32]int{1: 1, 2: 1}))
+	})
+
+	It("removes unused tilesets and compacts gids", func() {
+		removed, err := m.PruneTilesets()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(removed).To(Equal(1))
+
+		Expect(m.Tilesets).To(HaveLen(2))
+		Expect(m.Tilesets[0].FirstGID).To(Equal(GID(1)))
+		Expect(m.Tilesets[1].Name).To(Equal("props"))
+		Expect(m.Tilesets[1].FirstGID).To(Equal(GID(5)))
+
+		Expect(m.Layers[0].Data.DataTiles).To(Equal([]DataTile{
+			{GID: 1},
+			{GID: 1, HorizontalFlip: true},
+			{GID: 7},
+		}))
+		Expect(m.ObjectGroups[0].Objects[0].GID).To(Equal(6 | GIDVerticalFlip))
+		Expect(m.ObjectGroups[0].Objects[1].GID).To(BeZero())
+
+		tileset, err := m.GetTilesetForGID(7)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tileset.Name).To(Equal("props"))
+		_, err = m.GetTilesetForGID(9)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails for invalid gids without changing the map", func() {
+		Expect(m.Layers[0].SetTile(0, 0, 10, 0)).To(Succeed())
+		_, err := m.TilesetUsage()
+		Expect(err).To(HaveOccurred())
+
+		_, err = m.PruneTilesets()
+		Expect(err).To(HaveOccurred())
+		Expect(m.Tilesets).To(HaveLen(3))
+		Expect(m.Layers[0].Data.DataTiles[2].GID).To(Equal(GID(22)))
+	})
+
+	It("renders pruned maps identically", func() {
+		f, err := os.Open("testfiles/simple_example.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		loaded, err := NewMap(f)
+		Expect(err).ToNot(HaveOccurred())
+
+		//only the layer using the second tileset is kept
+		Expect(loaded.RemoveLayer(1)).To(Succeed())
+		Expect(loaded.RemoveLayer(0)).To(Succeed())
+		expected := NewImageCanvasFromMap(*loaded)
+		Expect(NewRenderer(*loaded, expected).Render(0)).To(Succeed())
+
+		removed, err := loaded.PruneTilesets()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(removed).To(Equal(1))
+		Expect(loaded.Tilesets[0].Name).To(Equal("chipset-copy"))
+		Expect(loaded.Tilesets[0].FirstGID).To(Equal(GID(1)))
+
+		c := NewImageCanvasFromMap(*loaded)
+		Expect(NewRenderer(*loaded, c).Render(0)).To(Succeed())
+		Expect(expected.Image()).To(EqualImage(c.Image()))
+	})
+})
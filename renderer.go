@@ -1,9 +1,12 @@
 package tmx
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"image"
-	"path/filepath"
+	"math"
+	"sync"
 )
 
 //Renderer renders
@@ -14,14 +17,100 @@ type Renderer interface {
 	Render(elapsedTime int64) error
 }
 
+//ContextRenderer is a Renderer that stops
+//rendering as soon as the context is done
+type ContextRenderer interface {
+	Renderer
+	RenderContext(ctx context.Context, elapsedTime int64) error
+}
+
+//ErrInvalidImageType is returned if a located image can't be sliced into tiles
+var ErrInvalidImageType = errors.New("invalid image type given")
+
+//ResourceError is returned by the renderer if the image
+//of a tileset could not be loaded
+type ResourceError struct {
+	//Tileset is the name of the tileset
+	Tileset string
+	//Path is the resolved path of the image
+	Path string
+	Err  error
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("could not load image %s of tileset %s: %v", e.Path, e.Tileset, e.Err)
+}
+
+//Unwrap returns the underlying error
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
 type fullRenderer struct {
 	canvas Canvas
 	m      Map
-	loader ResourceLocator
+	loader ContextResourceLocator
 	tf     TileFlipper
 	timer  *timer
+	opts   RenderOptions
+	images *transparencyCache
+}
+
+//LayerFilter decides if the layer with the given index
+//of Map.AllLayers will be drawn
+type LayerFilter func(index int, l Layer) bool
+
+//LayersByName selects all layers with one of the given names
+func LayersByName(names ...string) LayerFilter {
+	selected := map[string]bool{}
+	for _, n := range names {
+		selected[n] = true
+	}
+
+	return func(index int, l Layer) bool {
+		return selected[l.Name]
+	}
+}
+
+//LayersByIndex selects all layers at the given indices
+func LayersByIndex(indices ...int) LayerFilter {
+	selected := map[int]bool{}
+	for _, i := range indices {
+		selected[i] = true
+	}
+
+	return func(index int, l Layer) bool {
+		return selected[index]
+	}
 }
 
+//RenderOptions control what the renderer will draw
+type RenderOptions struct {
+	//Locator loads tileset images, defaults to a cached FilesystemLocator,
+	//it may also implement ContextResourceLocator
+	Locator ResourceLocator
+	//TileFlipper flips tiles, defaults to an imaging based implementation
+	TileFlipper TileFlipper
+	//Layers selects the layers to draw, all layers are drawn if nil
+	Layers LayerFilter
+	//SkipBackground will not fill the canvas with the background color
+	SkipBackground bool
+	//IgnoreVisibility draws selected layers even if they or their groups are invisible
+	IgnoreVisibility bool
+	//TileCache keeps sliced and flipped tiles between renders,
+	//tiles are sliced on every render if nil
+	TileCache *TileCache
+	//PathResolver resolves the paths of tileset images, defaults
+	//to the PathResolver the map was loaded with
+	PathResolver PathResolver
+	//Workers renders stripes of an ImageCanvas concurrently if
+	//greater than one, the Locator must be safe for concurrent use
+	Workers int
+}
+
+//stripeRows is the number of tile rows each worker renders at once
+const stripeRows = 4
+
 //FlipMode gives the mode to flip
 type FlipMode uint8
 
@@ -52,7 +141,7 @@ const (
 //NewRenderer lets you draw the map on a custom canvas
 //with a default FilesystemLocator
 func NewRenderer(m Map, c Canvas) Renderer {
-	return NewRendererWithResourceLocator(m, c, NewLazyResourceLocator(FilesystemLocator{}))
+	return NewRendererWithOptions(m, c, RenderOptions{})
 }
 
 //NewRendererWithResourceLocator return a new renderer
@@ -68,17 +157,98 @@ func NewRendererWithResourceLocatorAndTileFlipper(
 	locator ResourceLocator,
 	tf TileFlipper,
 ) Renderer {
-	return &fullRenderer{m: m, canvas: c, loader: locator, tf: tf}
+	return NewRendererWithOptions(m, c, RenderOptions{Locator: locator, TileFlipper: tf})
+}
+
+//NewRendererWithOptions allows you to specify
+//a custom canvas and all RenderOptions, e.g.
+//to draw only a subset of all layers
+func NewRendererWithOptions(m Map, c Canvas, opts RenderOptions) Renderer {
+	if opts.Locator == nil {
+		opts.Locator = NewLazyResourceLocator(FilesystemLocator{})
+	}
+
+	if opts.TileFlipper == nil {
+		opts.TileFlipper = &imagingFlipper{}
+	}
+
+	return &fullRenderer{
+		m:      m,
+		canvas: c,
+		loader: WithContext(opts.Locator),
+		tf:     opts.TileFlipper,
+		opts:   opts,
+		images: newTransparencyCache(opts.Locator),
+	}
 }
 
 //Render will generate a preview image of the tmx map provided
 func (r *fullRenderer) Render(elapsedTime int64) error {
+	return r.RenderContext(context.Background(), elapsedTime)
+}
+
+//RenderContext renders like Render but stops
+//as soon as ctx is done and returns its error
+func (r *fullRenderer) RenderContext(ctx context.Context, elapsedTime int64) error {
 	canvas := tilemap{subject: r.m}
-	canvas.renderBackground(r)
 	canvas.updateIdentities(elapsedTime)
-	err := canvas.renderLayer(r)
-	if err != nil {
-		return err
+
+	_, relative := r.canvas.(RelativeCanvas)
+	if imgCanvas, ok := r.canvas.(ImageCanvas); ok && !relative && r.opts.Workers > 1 {
+		return canvas.renderParallel(ctx, r, imgCanvas)
+	}
+
+	return canvas.render(ctx, r, r.canvas)
+}
+
+//render draws background and layers on c
+func (t *tilemap) render(ctx context.Context, r *fullRenderer, c Canvas) error {
+	if !r.opts.SkipBackground {
+		t.renderBackground(c)
+	}
+
+	return t.renderLayer(ctx, r, c)
+}
+
+//renderParallel splits the canvas into stripes that
+//are rendered concurrently by r.opts.Workers goroutines
+func (t *tilemap) renderParallel(ctx context.Context, r *fullRenderer, c ImageCanvas) error {
+	bounds := c.Bounds()
+	stripeHeight := t.subject.TileHeight * stripeRows
+	if stripeHeight <= 0 {
+		stripeHeight = bounds.Dy()
+	}
+
+	stripes := make(chan image.Rectangle)
+	errs := make(chan error, r.opts.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < r.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			for stripe := range stripes {
+				if err == nil {
+					err = t.render(ctx, r, clippedCanvas{ImageCanvas: c, clip: stripe})
+				}
+			}
+
+			errs <- err
+		}()
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stripeHeight {
+		stripes <- image.Rect(bounds.Min.X, y, bounds.Max.X, y+stripeHeight).Intersect(bounds)
+	}
+
+	close(stripes)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -92,9 +262,9 @@ type subImager interface {
 	SubImage(r image.Rectangle) image.Image
 }
 
-func (t tilemap) renderBackground(r *fullRenderer) {
+func (t tilemap) renderBackground(c Canvas) {
 	color := t.subject.BackgroundColor
-	r.canvas.FillRect(color, r.canvas.Bounds())
+	c.FillRect(color, c.Bounds())
 }
 
 func (t *tilemap) updateIdentities(elapsedTime int64) {
@@ -107,13 +277,123 @@ func (t *tilemap) updateIdentities(elapsedTime int64) {
 	}
 }
 
-func (t *tilemap) renderLayer(r *fullRenderer) error {
-	for _, l := range t.subject.Layers {
-		if !l.IsVisible() {
-			continue
+func (r *fullRenderer) shouldRenderLayer(index int, l Layer) bool {
+	if !r.opts.IgnoreVisibility && !l.IsVisible() {
+		return false
+	}
+
+	if r.opts.Layers == nil {
+		return true
+	}
+
+	return r.opts.Layers(index, l)
+}
+
+//drawnLayer is a tile layer with the offset and opacity of its groups
+type drawnLayer struct {
+	layer   *Layer
+	offset  image.Point
+	opacity float64
+}
+
+//drawnLayers returns the selected tile layers including those in groups
+//in the order they are drawn, layers of invisible groups are skipped
+//unless the visibility is ignored
+func (t *tilemap) drawnLayers(r *fullRenderer) []drawnLayer {
+	var layers []drawnLayer
+	index := 0
+	var add func(g *Group, offset image.Point, opacity float64, visible bool)
+	add = func(g *Group, offset image.Point, opacity float64, visible bool) {
+		for _, child := range g.children() {
+			switch {
+			case child.layer != nil:
+				if visible && r.shouldRenderLayer(index, *child.layer) {
+					layers = append(layers, drawnLayer{
+						layer:   child.layer,
+						offset:  offset,
+						opacity: opacity * layerOpacity(child.layer.Opacity),
+					})
+				}
+				index++
+			case child.group != nil:
+				nested := child.group
+				add(
+					nested,
+					offset.Add(image.Pt(int(math.Round(nested.OffsetX)), int(math.Round(nested.OffsetY)))),
+					opacity*layerOpacity(nested.Opacity),
+					visible && (r.opts.IgnoreVisibility || nested.IsVisible()),
+				)
+			}
 		}
+	}
+
+	root := t.subject.root()
+	add(&root, image.Point{}, 1, true)
+	return layers
+}
+
+//layerOpacity returns the opacity of a layer or group, unset opacities are fully opaque
+func layerOpacity(opacity float32) float64 {
+	if opacity == 0 {
+		return 1
+	}
+
+	return float64(opacity)
+}
+
+//layerCanvas returns c moved by the offset of l,
+//image canvases also draw with the opacity of l
+func layerCanvas(c Canvas, l drawnLayer) Canvas {
+	if relative, ok := c.(RelativeCanvas); ok {
+		if l.offset == (image.Point{}) {
+			return c
+		}
+
+		return relativeOffsetCanvas{Canvas: c, relative: relative, offset: l.offset}
+	}
+
+	imgCanvas, ok := c.(ImageCanvas)
+	if !ok {
+		return c
+	}
+
+	if l.offset != (image.Point{}) {
+		imgCanvas = offsetCanvas{ImageCanvas: imgCanvas, offset: l.offset}
+	}
+
+	if l.opacity < 1 {
+		imgCanvas = opacityCanvas{ImageCanvas: imgCanvas, opacity: l.opacity}
+	}
+
+	return imgCanvas
+}
+
+func (t *tilemap) renderLayer(ctx context.Context, r *fullRenderer, canvas Canvas) error {
+	//resolved image paths for every tileset
+	paths := map[*Tileset]string{}
+	_, relative := canvas.(RelativeCanvas)
+	_, clipped := canvas.(ImageCanvas)
+	clipped = clipped && !relative
+	overhang := t.overhang()
+	for _, drawn := range t.drawnLayers(r) {
+		l := *drawn.layer
+		c := layerCanvas(canvas, drawn)
+
+		//only the rows that can overlap the canvas are drawn,
+		//stripes of parallel renders skip all other rows
+		first, end := 0, len(l.Data.DataTiles)
+		if clipped {
+			first, end = t.tilesIn(l, c.Bounds(), overhang)
+		}
+
+		for i := first; i < end; i++ {
+			dt := l.Data.DataTiles[i]
+			if l.Width > 0 && i%l.Width == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
 
-		for i, dt := range l.Data.DataTiles {
 			tileset, err := t.subject.GetTilesetForGID(dt.GID)
 			if err != nil {
 				continue
@@ -131,16 +411,29 @@ func (t *tilemap) renderLayer(r *fullRenderer) error {
 				}
 			}
 
-			tx := tileID % tileset.GetNumTilesX()
-			ty := tileID / tileset.GetNumTilesX()
-			tx *= t.subject.TileWidth
-			ty *= t.subject.TileHeight
-
-			tileBounds := image.Rect(tx, ty, tx+t.subject.TileWidth, ty+t.subject.TileHeight)
 			x := (i % l.Width) * t.subject.TileWidth
 			y := (i / l.Width) * t.subject.TileHeight
 
 			bounds := image.Rect(x, y, x+t.subject.TileWidth, y+t.subject.TileWidth)
+			source := tileset.Image
+			var tileBounds image.Rectangle
+			if tileset.IsCollection() {
+				frame := tileset.GetTileByID(uint32(tileID))
+				if frame == nil {
+					continue
+				}
+
+				source = frame.Image
+				tileBounds = image.Rect(0, 0, source.Width, source.Height)
+				bounds = image.Rect(x, y+t.subject.TileHeight-source.Height, x+source.Width, y+t.subject.TileHeight)
+			} else {
+				tx := tileID % tileset.GetNumTilesX()
+				ty := tileID / tileset.GetNumTilesX()
+				tx *= t.subject.TileWidth
+				ty *= t.subject.TileHeight
+
+				tileBounds = image.Rect(tx, ty, tx+t.subject.TileWidth, ty+t.subject.TileHeight)
+			}
 
 			flipMode := FlipNone
 			if dt.DiagonalFlip {
@@ -155,34 +448,36 @@ func (t *tilemap) renderLayer(r *fullRenderer) error {
 				flipMode = FlipVertical
 			}
 
-			if relativeCanvas, ok := r.canvas.(RelativeCanvas); ok {
-				relativeCanvas.Draw(tileBounds, bounds, flipMode, tileset.GetFilename())
+			if relativeCanvas, ok := c.(RelativeCanvas); ok {
+				filename := tileset.GetFilename()
+				if tileset.IsCollection() {
+					filename = source.Source
+				}
+
+				relativeCanvas.Draw(tileBounds, bounds, flipMode, filename)
 				continue
 			}
 
 			//Legacy mode, draw images directly
-			if imgCanvas, ok := r.canvas.(ImageCanvas); ok {
-				tilesetgfx, err := r.loader.LocateResource(filepath.Clean(t.subject.filename + tileset.Image.Source))
-				if err != nil {
-					return errors.New("invalid tileset path")
+			if imgCanvas, ok := c.(ImageCanvas); ok {
+				if !bounds.Overlaps(imgCanvas.Bounds()) {
+					continue
 				}
-				ptileset, ok := tilesetgfx.(subImager)
-				if !ok {
-					return errors.New("invalid image type given")
-				}
-
-				tile := ptileset.SubImage(tileBounds)
 
-				if dt.DiagonalFlip {
-					tile = r.tf.FlipDiagonal(tile)
+				path, ok := paths[tileset]
+				if !ok {
+					path = r.imagePath(t.subject, tileset, source)
+					paths[tileset] = path
 				}
 
-				if dt.HorizontalFlip {
-					tile = r.tf.FlipHorizontal(tile)
+				if tileset.IsCollection() {
+					path = r.imagePath(t.subject, tileset, source)
+					tileBounds = image.Rectangle{}
 				}
 
-				if dt.VerticalFlip {
-					tile = r.tf.FlipVertical(tile)
+				tile, err := r.sliceTile(ctx, tileset.Name, path, source.Trans, tileID, tileBounds, dt)
+				if err != nil {
+					return err
 				}
 
 				imgCanvas.Draw(tile, bounds)
@@ -192,3 +487,129 @@ func (t *tilemap) renderLayer(r *fullRenderer) error {
 
 	return nil
 }
+
+//overhang returns how far tiles of image collections
+//reach into the rows above their own row in pixels
+func (t *tilemap) overhang() int {
+	overhang := 0
+	for _, tileset := range t.subject.Tilesets {
+		if !tileset.IsCollection() {
+			continue
+		}
+
+		for _, tile := range tileset.Tiles {
+			if h := tile.Image.Height - t.subject.TileHeight; h > overhang {
+				overhang = h
+			}
+		}
+	}
+
+	return overhang
+}
+
+//tilesIn returns the range of tile indices of l in the rows that
+//can overlap bounds, tiles may reach overhang pixels above their row
+func (t *tilemap) tilesIn(l Layer, bounds image.Rectangle, overhang int) (int, int) {
+	height := t.subject.TileHeight
+	if l.Width <= 0 || height <= 0 {
+		return 0, len(l.Data.DataTiles)
+	}
+
+	first := floorDiv(bounds.Min.Y, height)
+	end := floorDiv(bounds.Max.Y+overhang+height-1, height)
+	clamp := func(row int) int {
+		i := row * l.Width
+		if i < 0 {
+			return 0
+		}
+
+		if i > len(l.Data.DataTiles) {
+			return len(l.Data.DataTiles)
+		}
+
+		return i
+	}
+
+	return clamp(first), clamp(end)
+}
+
+//floorDiv divides a by b rounding towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+
+	return q
+}
+
+//imagePath resolves the path of an image of tileset
+func (r *fullRenderer) imagePath(m Map, tileset *Tileset, source Image) string {
+	base := m.filename
+	if tileset.filename != "" {
+		base = tileset.filename
+	}
+
+	resolver := r.opts.PathResolver
+	if resolver == nil {
+		resolver = resolverOrDefault(m.resolver)
+	}
+
+	return resolver.Resolve(base, source.Source)
+}
+
+//sliceTile returns the flipped tile at tileBounds of the
+//image at path, an empty tileBounds selects the whole image
+func (r *fullRenderer) sliceTile(
+	ctx context.Context,
+	tilesetName string,
+	path string,
+	trans string,
+	tileID int,
+	tileBounds image.Rectangle,
+	dt DataTile,
+) (image.Image, error) {
+	key := tileKey{tileset: path, trans: trans, tileID: tileID, bounds: tileBounds, flips: dt.flips()}
+	if r.opts.TileCache != nil {
+		if tile, ok := r.opts.TileCache.get(key); ok {
+			return tile, nil
+		}
+	}
+
+	tilesetgfx, err := r.images.locate(ctx, path, trans)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		return nil, &ResourceError{Tileset: tilesetName, Path: path, Err: err}
+	}
+	ptileset, ok := tilesetgfx.(subImager)
+	if !ok {
+		return nil, &ResourceError{Tileset: tilesetName, Path: path, Err: ErrInvalidImageType}
+	}
+
+	if tileBounds.Empty() {
+		tileBounds = tilesetgfx.Bounds()
+	}
+
+	tile := ptileset.SubImage(tileBounds)
+
+	if dt.DiagonalFlip {
+		tile = r.tf.FlipDiagonal(tile)
+	}
+
+	if dt.HorizontalFlip {
+		tile = r.tf.FlipHorizontal(tile)
+	}
+
+	if dt.VerticalFlip {
+		tile = r.tf.FlipVertical(tile)
+	}
+
+	if r.opts.TileCache != nil {
+		tile = r.opts.TileCache.put(key, tile)
+	}
+
+	return tile, nil
+}
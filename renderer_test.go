@@ -1,8 +1,16 @@
 package tmx_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
 	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
 
 	"image/png"
 
@@ -11,6 +19,59 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type countingResourceLocator struct {
+	parent ResourceLocator
+	calls  int
+}
+
+func (c *countingResourceLocator) LocateResource(filepath string) (image.Image, error) {
+	c.calls++
+	return c.parent.LocateResource(filepath)
+}
+
+//gatedResourceLocator loads the image at gated once release is closed
+type gatedResourceLocator struct {
+	mutex   sync.Mutex
+	gated   string
+	release chan struct{}
+	loaded  []string
+}
+
+func (g *gatedResourceLocator) LocateResource(filepath string) (image.Image, error) {
+	if filepath == g.gated {
+		<-g.release
+	}
+
+	g.mutex.Lock()
+	g.loaded = append(g.loaded, filepath)
+	g.mutex.Unlock()
+
+	return FilesystemLocator{}.LocateResource(filepath)
+}
+
+func (g *gatedResourceLocator) loadedPaths() []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return append([]string{}, g.loaded...)
+}
+
+//relativeCanvas records where tiles are drawn
+type relativeCanvas struct {
+	bounds image.Rectangle
+	drawn  []image.Rectangle
+}
+
+func (c *relativeCanvas) FillRect(what color.Color, where image.Rectangle) {}
+
+func (c *relativeCanvas) Bounds() image.Rectangle {
+	return c.bounds
+}
+
+func (c *relativeCanvas) Draw(tile image.Rectangle, where image.Rectangle, f FlipMode, tileset string) {
+	c.drawn = append(c.drawn, where)
+}
+
 var _ = Describe("Test public renderer", func() {
 	Context("Test render", func() {
 		validateMapWithImage := func(mapFile, imageFile string, elapsedTime int64) {
@@ -67,6 +128,342 @@ var _ = Describe("Test public renderer", func() {
 		})
 	})
 
+	Context("Test render options", func() {
+		var testMap *Map
+
+		BeforeEach(func() {
+			f, err := os.Open("./testfiles/animated_example_zlib.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			testMap, err = NewMap(f)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		renderWithOptions := func(c *ImgCanvas, opts RenderOptions) {
+			renderer := NewRendererWithOptions(*testMap, c, opts)
+			Expect(renderer.Render(0)).To(Succeed())
+		}
+
+		It("renders the same image when drawing layer by layer", func() {
+			expected := NewImageCanvasFromMap(*testMap)
+			renderWithOptions(expected, RenderOptions{})
+
+			actual := NewImageCanvasFromMap(*testMap)
+			renderWithOptions(actual, RenderOptions{Layers: LayersByIndex()})
+			for i := range testMap.Layers {
+				renderWithOptions(actual, RenderOptions{
+					Layers:         LayersByIndex(i),
+					SkipBackground: true,
+				})
+			}
+
+			Expect(actual.Image()).To(EqualImage(expected.Image()))
+		})
+
+		It("leaves the canvas transparent without background", func() {
+			c := NewImageCanvasFromMap(*testMap)
+			renderWithOptions(c, RenderOptions{
+				Layers:         LayersByName("Nothing"),
+				SkipBackground: true,
+			})
+
+			bounds := c.Image().Bounds()
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+					Expect(c.Image().RGBAAt(x, y).A).To(Equal(uint8(0)))
+				}
+			}
+		})
+
+		It("draws invisible layers only when asked", func() {
+			countOpaque := func(c *ImgCanvas) int {
+				count := 0
+				bounds := c.Image().Bounds()
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+						if c.Image().RGBAAt(x, y).A != 0 {
+							count++
+						}
+					}
+				}
+
+				return count
+			}
+
+			hidden := NewImageCanvasFromMap(*testMap)
+			renderWithOptions(hidden, RenderOptions{
+				Layers:         LayersByName("Invisible"),
+				SkipBackground: true,
+			})
+			Expect(countOpaque(hidden)).To(Equal(0))
+
+			forced := NewImageCanvasFromMap(*testMap)
+			renderWithOptions(forced, RenderOptions{
+				Layers:           LayersByName("Invisible"),
+				SkipBackground:   true,
+				IgnoreVisibility: true,
+			})
+			Expect(countOpaque(forced)).To(BeNumerically(">", 0))
+		})
+
+		It("accepts custom predicates", func() {
+			c := NewImageCanvasFromMap(*testMap)
+			called := []int{}
+			renderWithOptions(c, RenderOptions{
+				Layers: func(index int, l Layer) bool {
+					called = append(called, index)
+					return false
+				},
+			})
+			Expect(called).To(Equal([]int{0, 2, 3}))
+		})
+	})
+
+	Context("Test transparent colors", func() {
+		var (
+			testMap *Map
+			locator *countingResourceLocator
+			canvas  *ImgCanvas
+		)
+
+		BeforeEach(func() {
+			f, err := os.Open("./testfiles/transparent_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			testMap, err = NewMap(f)
+			Expect(err).ToNot(HaveOccurred())
+
+			locator = &countingResourceLocator{parent: FilesystemLocator{}}
+			canvas = NewImageCanvasFromMap(*testMap)
+			renderer := NewRendererWithOptions(*testMap, canvas, RenderOptions{
+				Locator:        locator,
+				SkipBackground: true,
+			})
+			Expect(renderer.Render(0)).To(Succeed())
+			Expect(renderer.Render(0)).To(Succeed())
+		})
+
+		It("removes the trans color of tileset images", func() {
+			Expect(canvas.Image().RGBAAt(0, 0).A).To(Equal(uint8(0)))
+			Expect(canvas.Image().RGBAAt(8, 8)).To(Equal(color.RGBA{0, 0, 255, 255}))
+			Expect(canvas.Image().RGBAAt(40, 8)).To(Equal(color.RGBA{255, 0, 0, 255}))
+		})
+
+		It("removes the trans color of per tile images", func() {
+			Expect(canvas.Image().RGBAAt(16, 0).A).To(Equal(uint8(0)))
+			Expect(canvas.Image().RGBAAt(16, 12)).To(Equal(color.RGBA{0, 255, 0, 255}))
+		})
+
+		It("converts every image only once", func() {
+			Expect(locator.calls).To(Equal(2))
+		})
+
+		It("stores converted images in the caching locator", func() {
+			counting := &countingResourceLocator{parent: FilesystemLocator{}}
+			cache := NewCachingResourceLocator(counting, CacheOptions{})
+			for i := 0; i < 2; i++ {
+				renderer := NewRendererWithOptions(*testMap, NewImageCanvasFromMap(*testMap), RenderOptions{Locator: cache})
+				Expect(renderer.Render(0)).To(Succeed())
+			}
+
+			Expect(counting.calls).To(Equal(2))
+			Expect(cache.Stats().Entries).To(Equal(2))
+
+			cache.UnsetResource("testfiles/trans_tiles.png")
+			Expect(cache.Stats().Entries).To(Equal(1))
+
+			limited := NewCachingResourceLocator(FilesystemLocator{}, CacheOptions{MaxEntries: 1})
+			renderer := NewRendererWithOptions(*testMap, NewImageCanvasFromMap(*testMap), RenderOptions{Locator: limited})
+			Expect(renderer.Render(0)).To(Succeed())
+			Expect(limited.Stats().Entries).To(Equal(1))
+			Expect(limited.Stats().Evictions).To(BeNumerically(">", 0))
+		})
+
+		It("loads other images while one image is loading", func() {
+			m := *testMap
+			m.Width, m.Height = 1, 8
+			m.Layers = []Layer{{Name: "Tiles", Width: 1, Height: 8, Data: Data{DataTiles: []DataTile{
+				{GID: 3}, {GID: 3}, {GID: 3}, {GID: 3}, {GID: 1}, {GID: 1}, {GID: 1}, {GID: 1},
+			}}}}
+
+			gated := &gatedResourceLocator{gated: "testfiles/trans_single.png", release: make(chan struct{})}
+			renderer := NewRendererWithOptions(m, NewImageCanvasFromMap(m), RenderOptions{
+				Locator: NewLazyResourceLocator(gated),
+				Workers: 2,
+			})
+
+			done := make(chan error, 1)
+			go func() {
+				done <- renderer.Render(0)
+			}()
+
+			Eventually(gated.loadedPaths).Should(Equal([]string{"testfiles/trans_tiles.png"}))
+			close(gated.release)
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("Test groups", func() {
+		var testMap *Map
+
+		BeforeEach(func() {
+			testMap = mustLoadTestMap("testfiles/groups.tmx")
+		})
+
+		render := func(opts RenderOptions) *image.RGBA {
+			c := NewImageCanvasFromMap(*testMap)
+			Expect(NewRendererWithOptions(*testMap, c, opts).Render(0)).To(Succeed())
+			return c.Image()
+		}
+
+		It("draws grouped layers with the offset and opacity of their groups", func() {
+			ground := render(RenderOptions{Layers: LayersByName("Ground")})
+			actual := render(RenderOptions{})
+			Expect(actual.At(16, 16)).To(Equal(ground.At(16, 16)))
+			Expect(actual.At(48, 16)).ToNot(Equal(ground.At(48, 16)))
+
+			testMap.Groups[0].Opacity = 0
+			opaque := render(RenderOptions{})
+			Expect(opaque.At(48, 16)).ToNot(Equal(ground.At(48, 16)))
+			Expect(actual.At(48, 16)).ToNot(Equal(opaque.At(48, 16)))
+		})
+
+		It("draws layers of invisible groups only when asked", func() {
+			ground := render(RenderOptions{Layers: LayersByName("Ground")})
+			Expect(render(RenderOptions{}).At(16, 48)).To(Equal(ground.At(16, 48)))
+			Expect(render(RenderOptions{IgnoreVisibility: true}).At(16, 48)).ToNot(Equal(ground.At(16, 48)))
+		})
+
+		It("counts grouped layers for layer filters", func() {
+			called := []int{}
+			render(RenderOptions{
+				IgnoreVisibility: true,
+				Layers: func(index int, l Layer) bool {
+					called = append(called, index)
+					return l.Name == "Secret"
+				},
+			})
+			Expect(called).To(Equal([]int{0, 1, 2}))
+		})
+
+		It("moves tiles of relative canvases", func() {
+			c := &relativeCanvas{bounds: image.Rect(0, 0, 64, 64)}
+			Expect(NewRenderer(*testMap, c).Render(0)).To(Succeed())
+			Expect(c.drawn).To(ContainElement(image.Rect(32, 0, 64, 32)))
+			Expect(c.drawn).To(HaveLen(5))
+		})
+	})
+
+	Context("Test parallel rendering", func() {
+		render := func(testMap *Map, opts RenderOptions) *ImgCanvas {
+			c := NewImageCanvasFromMap(*testMap)
+			renderer := NewRendererWithOptions(*testMap, c, opts)
+			Expect(renderer.Render(101)).To(Succeed())
+
+			return c
+		}
+
+		for _, mapFile := range []string{
+			"./testfiles/simple_example.tmx",
+			"./testfiles/simple_example_zlib.tmx",
+			"./testfiles/uncompressed_not_square.tmx",
+			"./testfiles/animated_example_zlib.tmx",
+			"./testfiles/transparent_example.tmx",
+			"./testfiles/groups.tmx",
+		} {
+			mapFile := mapFile
+			It("renders the same image as the sequential renderer for "+mapFile, func() {
+				testMap, err := loadTestMap(mapFile)
+				Expect(err).ToNot(HaveOccurred())
+				expected := render(testMap, RenderOptions{})
+
+				for _, workers := range []int{2, 3, 8} {
+					testMap, err := loadTestMap(mapFile)
+					Expect(err).ToNot(HaveOccurred())
+					actual := render(testMap, RenderOptions{Workers: workers, TileCache: NewTileCache(0)})
+					Expect(actual.Image().Pix).To(Equal(expected.Image().Pix))
+				}
+			})
+		}
+
+		It("reports errors of workers", func() {
+			testMap, err := loadTestMap("./testfiles/simple_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			c := NewImageCanvasFromMap(*testMap)
+			renderer := NewRendererWithOptions(*testMap, c, RenderOptions{
+				Workers: 4,
+				Locator: nilResourceLoader{},
+			})
+			Expect(renderer.Render(0)).ToNot(Succeed())
+		})
+	})
+
+	Context("Test render errors", func() {
+		var testMap *Map
+
+		BeforeEach(func() {
+			var err error
+			testMap, err = loadTestMap("./testfiles/simple_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		renderWith := func(ctx context.Context, locator ResourceLocator) error {
+			c := NewImageCanvasFromMap(*testMap)
+			renderer := NewRendererWithResourceLocator(*testMap, c, locator)
+			contextRenderer, ok := renderer.(ContextRenderer)
+			Expect(ok).To(BeTrue())
+
+			return contextRenderer.RenderContext(ctx, 0)
+		}
+
+		It("wraps errors of the locator", func() {
+			err := renderWith(context.Background(), FSLocator{FS: fstest.MapFS{}})
+			Expect(errors.Is(err, fs.ErrNotExist)).To(BeTrue())
+
+			var resourceErr *ResourceError
+			Expect(errors.As(err, &resourceErr)).To(BeTrue())
+			Expect(resourceErr.Tileset).To(Equal("chipset"))
+			Expect(resourceErr.Path).To(Equal("testfiles/chipset.png"))
+			Expect(err.Error()).To(ContainSubstring("chipset.png"))
+		})
+
+		It("reports invalid images", func() {
+			err := renderWith(context.Background(), nilResourceLoader{})
+			Expect(errors.Is(err, ErrInvalidImageType)).To(BeTrue())
+		})
+
+		It("stops when the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := renderWith(ctx, FilesystemLocator{})
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		})
+	})
+
+	Context("Test context locator", func() {
+		It("calls the wrapped locator", func() {
+			locator := &countingResourceLocator{parent: FilesystemLocator{}}
+			img, err := WithContext(locator).LocateResourceContext(context.Background(), "testfiles/chipset.png")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).ToNot(BeNil())
+			Expect(locator.calls).To(Equal(1))
+		})
+
+		It("does not call the wrapped locator for done contexts", func() {
+			locator := &countingResourceLocator{parent: FilesystemLocator{}}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := WithContext(locator).LocateResourceContext(ctx, "testfiles/chipset.png")
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(locator.calls).To(Equal(0))
+		})
+
+		It("keeps locators that support contexts", func() {
+			locator := NewCachingResourceLocator(FilesystemLocator{}, CacheOptions{})
+			Expect(WithContext(locator)).To(BeIdenticalTo(locator))
+		})
+	})
+
 	Context("Test flip mode", func() {
 		It("will have a working String", func() {
 			Expect(fmt.Sprintf("%s", FlipNone)).To(Equal("None"))
@@ -76,3 +473,54 @@ var _ = Describe("Test public renderer", func() {
 		})
 	})
 })
+
+//largeTestMap repeats the layers of the simple example
+//factor times in both directions
+func largeTestMap(b *testing.B, factor int) *Map {
+	testMap, err := loadTestMap("./testfiles/simple_example.tmx")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i, l := range testMap.Layers {
+		tiles := make([]DataTile, 0, len(l.Data.DataTiles)*factor*factor)
+		for y := 0; y < l.Height*factor; y++ {
+			for x := 0; x < l.Width*factor; x++ {
+				tiles = append(tiles, l.Data.DataTiles[(y%l.Height)*l.Width+x%l.Width])
+			}
+		}
+
+		testMap.Layers[i].Data.DataTiles = tiles
+		testMap.Layers[i].Width *= factor
+		testMap.Layers[i].Height *= factor
+	}
+
+	testMap.Width *= factor
+	testMap.Height *= factor
+
+	return testMap
+}
+
+func benchmarkRenderWorkers(b *testing.B, workers int) {
+	testMap := largeTestMap(b, 8)
+	c := NewImageCanvasFromMap(*testMap)
+	renderer := NewRendererWithOptions(*testMap, c, RenderOptions{
+		Workers:   workers,
+		TileCache: NewTileCache(0),
+	})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := renderer.Render(0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderLargeMapSequential(b *testing.B) {
+	benchmarkRenderWorkers(b, 1)
+}
+
+func BenchmarkRenderLargeMapParallel(b *testing.B) {
+	benchmarkRenderWorkers(b, 4)
+}
@@ -0,0 +1,75 @@
+package tmx
+
+import (
+	"path"
+	"strings"
+)
+
+//PathResolver resolves the paths of files referenced by maps,
+//tilesets and templates. All paths are slash separated.
+type PathResolver interface {
+	//Resolve returns the path of ref which is referenced by the file at base
+	Resolve(base, ref string) string
+}
+
+//DefaultPathResolver resolves paths relative to the file that
+//references them. Backslashes of windows paths are converted
+//and absolute paths are kept.
+type DefaultPathResolver struct {
+	//Roots replaces path prefixes after resolving,
+	//e.g. "C:/Users/artist/assets" to "assets"
+	Roots map[string]string
+}
+
+//Resolve to implement PathResolver interface
+func (d DefaultPathResolver) Resolve(base, ref string) string {
+	ref = strings.Replace(ref, "\\", "/", -1)
+	if !isAbsolutePath(ref) {
+		ref = path.Join(path.Dir(strings.Replace(base, "\\", "/", -1)), ref)
+	}
+
+	return d.remap(path.Clean(ref))
+}
+
+//remap replaces the longest matching root of p, it is called for
+//every tile of collections and must not allocate for other paths
+func (d DefaultPathResolver) remap(p string) string {
+	longest, match := "", ""
+	for root := range d.Roots {
+		if len(root) <= len(longest) {
+			continue
+		}
+
+		from := path.Clean(strings.Replace(root, "\\", "/", -1))
+		if p != from && !(strings.HasPrefix(p, from) && (strings.HasSuffix(from, "/") || p[len(from)] == '/')) {
+			continue
+		}
+
+		longest, match = root, from
+	}
+
+	if longest == "" {
+		return p
+	}
+
+	return path.Join(d.Roots[longest], strings.TrimPrefix(p, match))
+}
+
+//isAbsolutePath returns true for unix and windows absolute paths
+func isAbsolutePath(p string) bool {
+	if path.IsAbs(p) {
+		return true
+	}
+
+	return len(p) >= 3 && p[1] == ':' && p[2] == '/' &&
+		(p[0] >= 'a' && p[0] <= 'z' || p[0] >= 'A' && p[0] <= 'Z')
+}
+
+//resolverOrDefault returns r or a DefaultPathResolver if r is nil
+func resolverOrDefault(r PathResolver) PathResolver {
+	if r == nil {
+		return DefaultPathResolver{}
+	}
+
+	return r
+}
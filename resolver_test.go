@@ -0,0 +1,110 @@
+package tmx_test
+
+import (
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+//recordingResolver remembers all resolved references
+type recordingResolver struct {
+	DefaultPathResolver
+	refs []string
+}
+
+func (r *recordingResolver) Resolve(base, ref string) string {
+	r.refs = append(r.refs, ref)
+	return r.DefaultPathResolver.Resolve(base, ref)
+}
+
+var _ = Describe("Test path resolver", func() {
+	Context("Test default path resolver", func() {
+		resolver := DefaultPathResolver{Roots: map[string]string{
+			"C:/Users/artist/assets":    "assets",
+			"C:/Users/artist/assets/ui": "ui",
+			"/home/artist":              "home",
+		}}
+
+		It("resolves relative to the referencing file", func() {
+			Expect(resolver.Resolve("maps/level.tmx", "../tiles/a.png")).To(Equal("tiles/a.png"))
+			Expect(resolver.Resolve("maps/level.tmx", "a.png")).To(Equal("maps/a.png"))
+			Expect(resolver.Resolve("level.tmx", "./a.png")).To(Equal("a.png"))
+			Expect(resolver.Resolve("", "a.png")).To(Equal("a.png"))
+		})
+
+		It("converts backslashes", func() {
+			Expect(resolver.Resolve("maps\\level.tmx", "..\\tiles\\a.png")).To(Equal("tiles/a.png"))
+		})
+
+		It("keeps absolute paths", func() {
+			Expect(resolver.Resolve("maps/level.tmx", "/srv/a.png")).To(Equal("/srv/a.png"))
+			Expect(resolver.Resolve("maps/level.tmx", "D:\\a.png")).To(Equal("D:/a.png"))
+		})
+
+		It("remaps the longest matching root", func() {
+			Expect(resolver.Resolve("maps/level.tmx", "C:\\Users\\artist\\assets\\a.png")).To(Equal("assets/a.png"))
+			Expect(resolver.Resolve("maps/level.tmx", "C:\\Users\\artist\\assets\\ui\\a.png")).To(Equal("ui/a.png"))
+			Expect(resolver.Resolve("/home/artist/maps/level.tmx", "a.png")).To(Equal("home/maps/a.png"))
+		})
+
+		It("only remaps complete directories", func() {
+			Expect(resolver.Resolve("maps/level.tmx", "C:/Users/artist/assets2/a.png")).To(Equal("C:/Users/artist/assets2/a.png"))
+		})
+	})
+
+	Context("Test resolving map files", func() {
+		expectRendering := func(m *Map, opts RenderOptions) {
+			c := NewImageCanvasFromMap(*m)
+			Expect(NewRendererWithOptions(*m, c, opts).Render(0)).To(Succeed())
+			expectSimpleExampleImage(c.Image())
+		}
+
+		It("loads files referenced with backslashes", func() {
+			resolver := &recordingResolver{}
+			f, err := os.Open("testfiles/windows/backslash_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			m, err := NewMapWithOptions(f, LoadOptions{PathResolver: resolver})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Tilesets[0].Image.Width).To(Equal(320))
+			Expect(m.ObjectGroups[0].Objects[0].Name).To(Equal("chest"))
+			Expect(resolver.refs).To(ContainElement("..\\tilesets\\chipset.tsx"))
+
+			expectRendering(m, RenderOptions{})
+			Expect(resolver.refs).To(ContainElement("../chipset.png"))
+		})
+
+		It("remaps absolute asset roots", func() {
+			f, err := os.Open("testfiles/windows/absolute_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			m, err := NewMap(f)
+			Expect(err).ToNot(HaveOccurred())
+
+			expectRendering(m, RenderOptions{PathResolver: DefaultPathResolver{
+				Roots: map[string]string{"C:/Users/artist/tmx": "testfiles"},
+			}})
+		})
+
+		It("uses the resolver of the load options", func() {
+			f, err := os.Open("testfiles/windows/absolute_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			m, err := NewMapWithOptions(f, LoadOptions{PathResolver: DefaultPathResolver{
+				Roots: map[string]string{"C:/Users/artist/tmx": "testfiles"},
+			}})
+			Expect(err).ToNot(HaveOccurred())
+
+			expectRendering(m, RenderOptions{})
+		})
+
+		It("resolves paths inside of a fs.FS", func() {
+			fsys := os.DirFS("testfiles")
+			m, err := LoadMapFS(fsys, "windows/backslash_example.tmx")
+			Expect(err).ToNot(HaveOccurred())
+			expectRendering(m, RenderOptions{Locator: FSLocator{FS: fsys}})
+		})
+	})
+})
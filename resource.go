@@ -1,7 +1,9 @@
 package tmx
 
 import (
+	"context"
 	"image"
+	"image/color"
 	"os"
 
 	//import for gif support
@@ -18,6 +20,34 @@ type ResourceLocator interface {
 	LocateResource(filepath string) (image.Image, error)
 }
 
+//ContextResourceLocator can be implemented by locators
+//that can cancel loading once the context is done
+type ContextResourceLocator interface {
+	LocateResourceContext(ctx context.Context, filepath string) (image.Image, error)
+}
+
+//WithContext returns l if it is a ContextResourceLocator,
+//otherwise l is wrapped and only called if the context is not done yet
+func WithContext(l ResourceLocator) ContextResourceLocator {
+	if c, ok := l.(ContextResourceLocator); ok {
+		return c
+	}
+
+	return contextLocator{parent: l}
+}
+
+type contextLocator struct {
+	parent ResourceLocator
+}
+
+func (c contextLocator) LocateResourceContext(ctx context.Context, filepath string) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.parent.LocateResource(filepath)
+}
+
 //ResourceManager allows better memory handling
 //and cleanup of resources
 type ResourceManager interface {
@@ -35,37 +65,65 @@ func (f FilesystemLocator) LocateResource(filepath string) (image.Image, error)
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 
 	data, _, err := image.Decode(file)
 	return data, err
 }
 
-type lazyLocator struct {
-	parent   ResourceLocator
-	tilesets map[string]image.Image
+//NewLazyResourceLocator wraps a ResourceLocator and caches results
+//without any limits, see NewCachingResourceLocator
+func NewLazyResourceLocator(l ResourceLocator) ResourceLocator {
+	return NewCachingResourceLocator(l, CacheOptions{})
 }
 
-func (l *lazyLocator) LocateResource(filepath string) (image.Image, error) {
-	cached, ok := l.tilesets[filepath]
-	if ok {
-		return cached, nil
-	}
+//transparencyCache converts the transparent color of loaded images to
+//alpha, every image will only be converted once. Converted images are
+//stored in the CachingLocator of the renderer and count against its
+//limits, other locators get an own CachingLocator without limits.
+type transparencyCache struct {
+	loader    ContextResourceLocator
+	converted *CachingLocator
+}
 
-	data, err := l.parent.LocateResource(filepath)
-	if err != nil {
-		return nil, err
+func newTransparencyCache(l ResourceLocator) *transparencyCache {
+	converted, ok := l.(*CachingLocator)
+	if !ok {
+		converted = NewCachingResourceLocator(l, CacheOptions{})
 	}
 
-	l.tilesets[filepath] = data
-
-	return data, nil
+	return &transparencyCache{loader: WithContext(l), converted: converted}
 }
 
-func (l *lazyLocator) UnsetResource(filepath string) {
-	delete(l.tilesets, filepath)
+//locate loads the image from filepath and removes the trans color if set
+func (c *transparencyCache) locate(ctx context.Context, filepath string, trans string) (image.Image, error) {
+	if trans == "" {
+		return c.loader.LocateResourceContext(ctx, filepath)
+	}
+
+	return c.converted.locateConverted(ctx, filepath, trans)
 }
 
-//NewLazyResourceLocator wraps a ResourceLocator and caches results
-func NewLazyResourceLocator(l ResourceLocator) ResourceLocator {
-	return &lazyLocator{parent: l, tilesets: map[string]image.Image{}}
+//applyTransparency returns a copy of img where every
+//pixel matching trans is fully transparent
+func applyTransparency(img image.Image, trans hexcolor) image.Image {
+	r, g, b, ok := trans.parse()
+	if img == nil || !ok {
+		return img
+	}
+
+	bounds := img.Bounds()
+	target := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.R == r && c.G == g && c.B == b {
+				c = color.NRGBA{}
+			}
+
+			target.SetNRGBA(x, y, c)
+		}
+	}
+
+	return target
 }
@@ -1,10 +1,13 @@
 package tmx_test
 
 import (
+	"context"
 	"errors"
 	"image"
 	"io"
 	"os"
+	"sync"
+	"time"
 
 	. "github.com/manyminds/tmx"
 	. "github.com/onsi/ginkgo"
@@ -33,6 +36,65 @@ func (m *moduloErrorResourceLoader) LocateResource(filepath string) (image.Image
 	return nil, nil
 }
 
+//sizedResourceLoader returns images of size x size pixels
+//and counts how often each file was loaded
+type sizedResourceLoader struct {
+	mutex sync.Mutex
+	size  int
+	delay time.Duration
+	calls map[string]int
+}
+
+func (s *sizedResourceLoader) LocateResource(filepath string) (image.Image, error) {
+	s.mutex.Lock()
+	if s.calls == nil {
+		s.calls = map[string]int{}
+	}
+	s.calls[filepath]++
+	s.mutex.Unlock()
+
+	time.Sleep(s.delay)
+	if filepath == "panic" {
+		panic("loader failed")
+	}
+
+	if filepath == "missing" {
+		return nil, os.ErrNotExist
+	}
+
+	return image.NewRGBA(image.Rect(0, 0, s.size, s.size)), nil
+}
+
+//blockingResourceLoader loads images once release is closed
+//or fails with the error of the context
+type blockingResourceLoader struct {
+	sizedResourceLoader
+	release chan struct{}
+}
+
+func (b *blockingResourceLoader) LocateResourceContext(ctx context.Context, filepath string) (image.Image, error) {
+	b.mutex.Lock()
+	if b.calls == nil {
+		b.calls = map[string]int{}
+	}
+	b.calls[filepath]++
+	b.mutex.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-b.release:
+		return image.NewRGBA(image.Rect(0, 0, b.size, b.size)), nil
+	}
+}
+
+func (s *sizedResourceLoader) callsFor(filepath string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.calls[filepath]
+}
+
 var _ = Describe("Test public api", func() {
 	Context("check types for interface interface", func() {
 		It("can be implemented", func() {
@@ -104,4 +166,140 @@ var _ = Describe("Test public api", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
+
+	Context("test caching resource locator", func() {
+		var loader *sizedResourceLoader
+
+		BeforeEach(func() {
+			loader = &sizedResourceLoader{size: 10}
+		})
+
+		It("counts hits and misses", func() {
+			cache := NewCachingResourceLocator(loader, CacheOptions{})
+			for i := 0; i < 3; i++ {
+				_, err := cache.LocateResource("a")
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			_, err := cache.LocateResource("b")
+			Expect(err).ToNot(HaveOccurred())
+
+			stats := cache.Stats()
+			Expect(stats.Hits).To(Equal(uint64(2)))
+			Expect(stats.Misses).To(Equal(uint64(2)))
+			Expect(stats.Entries).To(Equal(2))
+			Expect(stats.Bytes).To(Equal(int64(2 * 10 * 10 * 4)))
+		})
+
+		It("does not cache errors", func() {
+			cache := NewCachingResourceLocator(loader, CacheOptions{})
+			_, err := cache.LocateResource("missing")
+			Expect(err).To(MatchError(os.ErrNotExist))
+			_, err = cache.LocateResource("missing")
+			Expect(err).To(HaveOccurred())
+			Expect(loader.callsFor("missing")).To(Equal(2))
+			Expect(cache.Stats().Entries).To(Equal(0))
+		})
+
+		It("evicts the least recently used entry by count", func() {
+			cache := NewCachingResourceLocator(loader, CacheOptions{MaxEntries: 2})
+			cache.LocateResource("a")
+			cache.LocateResource("b")
+			cache.LocateResource("a")
+			cache.LocateResource("c")
+
+			Expect(cache.Stats().Entries).To(Equal(2))
+			Expect(cache.Stats().Evictions).To(Equal(uint64(1)))
+
+			cache.LocateResource("a")
+			Expect(loader.callsFor("a")).To(Equal(1))
+			cache.LocateResource("b")
+			Expect(loader.callsFor("b")).To(Equal(2))
+		})
+
+		It("evicts entries by size", func() {
+			cache := NewCachingResourceLocator(loader, CacheOptions{MaxBytes: 2 * 10 * 10 * 4})
+			cache.LocateResource("a")
+			cache.LocateResource("b")
+			cache.LocateResource("c")
+
+			stats := cache.Stats()
+			Expect(stats.Entries).To(Equal(2))
+			Expect(stats.Bytes).To(BeNumerically("<=", 2*10*10*4))
+		})
+
+		It("can unset resources", func() {
+			cache := NewCachingResourceLocator(loader, CacheOptions{})
+			cache.LocateResource("a")
+			cache.UnsetResource("a")
+			Expect(cache.Stats().Entries).To(Equal(0))
+			Expect(cache.Stats().Bytes).To(Equal(int64(0)))
+			cache.LocateResource("a")
+			Expect(loader.callsFor("a")).To(Equal(2))
+		})
+
+		It("releases waiting callers if the parent panics", func() {
+			cache := NewCachingResourceLocator(loader, CacheOptions{})
+			Expect(func() { cache.LocateResource("panic") }).To(Panic())
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(func() { cache.LocateResource("panic") }).To(Panic())
+			}()
+
+			Eventually(done).Should(BeClosed())
+			Expect(loader.callsFor("panic")).To(Equal(2))
+			Expect(cache.Stats().Entries).To(Equal(0))
+		})
+
+		It("retries if the loading caller is canceled", func() {
+			blocking := &blockingResourceLoader{sizedResourceLoader: sizedResourceLoader{size: 10}, release: make(chan struct{})}
+			cache := NewCachingResourceLocator(blocking, CacheOptions{})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			first := make(chan error, 1)
+			go func() {
+				_, err := cache.LocateResourceContext(ctx, "a")
+				first <- err
+			}()
+			Eventually(func() int { return blocking.callsFor("a") }).Should(Equal(1))
+
+			second := make(chan error, 1)
+			go func() {
+				_, err := cache.LocateResourceContext(context.Background(), "a")
+				second <- err
+			}()
+			Eventually(func() uint64 { return cache.Stats().Hits }).Should(Equal(uint64(1)))
+
+			cancel()
+			Eventually(first).Should(Receive(MatchError(context.Canceled)))
+			Eventually(func() int { return blocking.callsFor("a") }).Should(Equal(2))
+			close(blocking.release)
+			Eventually(second).Should(Receive(BeNil()))
+			Expect(cache.Stats().Entries).To(Equal(1))
+		})
+
+		It("loads concurrently requested resources only once", func() {
+			loader.delay = 20 * time.Millisecond
+			cache := NewCachingResourceLocator(loader, CacheOptions{})
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					img, err := cache.LocateResource("a")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(img).ToNot(BeNil())
+				}()
+			}
+
+			wg.Wait()
+			Expect(loader.callsFor("a")).To(Equal(1))
+			Expect(cache.Stats().Misses).To(Equal(uint64(1)))
+		})
+	})
 })
@@ -0,0 +1,219 @@
+package tmx
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+//DefaultCellSize is the cell size of object indices in pixels if none is given
+const DefaultCellSize = 128
+
+//ObjectIndex is a spatial index of objects. Space is divided into square
+//cells and every object is stored in all cells its bounding box overlaps.
+//Objects are identified by their ID and queries use their exact shapes.
+type ObjectIndex struct {
+	cellSize float64
+	cells    map[image.Point][]int
+	objects  map[int]indexedObject
+}
+
+//indexedObject is an object with its shape in world space
+type indexedObject struct {
+	object Object
+	shape  CollisionShape
+	//cells the object is stored in
+	cells image.Rectangle
+}
+
+//NewObjectIndex creates an index with the given cell size in pixels
+//containing the objects of all groups. Good cell sizes are a bit
+//larger than most objects, DefaultCellSize is used if it is 0.
+func NewObjectIndex(cellSize float64, groups ...ObjectGroup) (*ObjectIndex, error) {
+	if cellSize == 0 {
+		cellSize = DefaultCellSize
+	}
+
+	if cellSize < 0 || math.IsInf(cellSize, 0) || math.IsNaN(cellSize) {
+		return nil, fmt.Errorf("invalid cell size %f", cellSize)
+	}
+
+	index := &ObjectIndex{
+		cellSize: cellSize,
+		cells:    map[image.Point][]int{},
+		objects:  map[int]indexedObject{},
+	}
+
+	for _, g := range groups {
+		for _, o := range g.Objects {
+			if err := index.Insert(o); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return index, nil
+}
+
+//Len returns the number of indexed objects
+func (x ObjectIndex) Len() int {
+	return len(x.objects)
+}
+
+//Get returns the indexed object with the given id
+func (x ObjectIndex) Get(id int) (Object, bool) {
+	indexed, ok := x.objects[id]
+	return indexed.object, ok
+}
+
+//Insert adds o to the index, its ID must not be 0 or in use
+func (x *ObjectIndex) Insert(o Object) error {
+	if o.ID == 0 {
+		return fmt.Errorf("object %s has no id", o.Name)
+	}
+
+	if _, ok := x.objects[o.ID]; ok {
+		return fmt.Errorf("object %d is already indexed", o.ID)
+	}
+
+	shape, err := o.collisionShape()
+	if err != nil {
+		return err
+	}
+
+	min, max := shape.Bounds()
+	indexed := indexedObject{object: o, shape: shape, cells: x.cellsOf(min, max)}
+	x.objects[o.ID] = indexed
+	eachCell(indexed.cells, func(cell image.Point) {
+		x.cells[cell] = append(x.cells[cell], o.ID)
+	})
+
+	return nil
+}
+
+//Update replaces the indexed object with the ID of o,
+//it has to be called after an object moved or changed its shape
+func (x *ObjectIndex) Update(o Object) error {
+	old, ok := x.objects[o.ID]
+	if !ok {
+		return fmt.Errorf("object %d is not indexed", o.ID)
+	}
+
+	x.Remove(o.ID)
+	if err := x.Insert(o); err != nil {
+		x.Insert(old.object)
+		return err
+	}
+
+	return nil
+}
+
+//Remove removes the object with the given id and
+//returns false if there was no such object
+func (x *ObjectIndex) Remove(id int) bool {
+	indexed, ok := x.objects[id]
+	if !ok {
+		return false
+	}
+
+	delete(x.objects, id)
+	eachCell(indexed.cells, func(cell image.Point) {
+		ids := x.cells[cell]
+		for i, other := range ids {
+			if other == id {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+
+		if len(ids) == 0 {
+			delete(x.cells, cell)
+		} else {
+			x.cells[cell] = ids
+		}
+	})
+
+	return true
+}
+
+//QueryRect returns all objects overlapping or touching
+//the axis aligned rectangle from min to max sorted by ID
+func (x ObjectIndex) QueryRect(min, max Vertex) []Object {
+	return x.query(min, max, func(s CollisionShape) bool {
+		return s.IntersectsRect(min, max)
+	})
+}
+
+//QueryPoint returns all objects containing p sorted by ID
+func (x ObjectIndex) QueryPoint(p Vertex) []Object {
+	return x.query(p, p, func(s CollisionShape) bool {
+		return s.Contains(p)
+	})
+}
+
+//QueryRadius returns all objects overlapping or touching
+//the circle around center with the given radius sorted by ID
+func (x ObjectIndex) QueryRadius(center Vertex, radius float64) []Object {
+	min := Vertex{X: center.X - radius, Y: center.Y - radius}
+	max := Vertex{X: center.X + radius, Y: center.Y + radius}
+	return x.query(min, max, func(s CollisionShape) bool {
+		return s.IntersectsCircle(center, radius)
+	})
+}
+
+//query returns all objects in the cells overlapping the
+//bounding box from min to max whose shape matches
+func (x ObjectIndex) query(min, max Vertex, matches func(CollisionShape) bool) []Object {
+	checked := map[int]bool{}
+	var result []Object
+	check := func(ids []int) {
+		for _, id := range ids {
+			if checked[id] {
+				continue
+			}
+
+			checked[id] = true
+			if indexed := x.objects[id]; matches(indexed.shape) {
+				result = append(result, indexed.object)
+			}
+		}
+	}
+
+	//large areas are faster checked by iterating all used cells
+	cells := x.cellsOf(min, max)
+	if cells.Dx()*cells.Dy() > len(x.cells) {
+		for cell, ids := range x.cells {
+			if cell.In(cells) {
+				check(ids)
+			}
+		}
+	} else {
+		eachCell(cells, func(cell image.Point) {
+			check(x.cells[cell])
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+//cellsOf returns the cells overlapped by the bounding box from min to max
+func (x ObjectIndex) cellsOf(min, max Vertex) image.Rectangle {
+	return image.Rect(
+		int(math.Floor(min.X/x.cellSize)), int(math.Floor(min.Y/x.cellSize)),
+		int(math.Floor(max.X/x.cellSize))+1, int(math.Floor(max.Y/x.cellSize))+1,
+	)
+}
+
+//eachCell calls f for all cells of r
+func eachCell(r image.Rectangle, f func(cell image.Point)) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			f(image.Pt(x, y))
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package tmx_test
+
+import (
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test object index", func() {
+	var index *ObjectIndex
+
+	ids := func(objects []Object) []int {
+		result := []int{}
+		for _, o := range objects {
+			result = append(result, o.ID)
+		}
+
+		return result
+	}
+
+	BeforeEach(func() {
+		var err error
+		index, err = NewObjectIndex(64, ObjectGroup{Objects: []Object{
+			{ID: 1, Name: "crate", X: 0, Y: 0, Width: 32, Height: 32},
+			{ID: 2, Name: "pond", X: 100, Y: 0, Width: 60, Height: 20, Ellipse: &struct{}{}},
+		}}, ObjectGroup{Objects: []Object{
+			{ID: 3, Name: "ramp", X: 200, Y: 100, Polygons: []Polygon{{Points: "0,0 40,0 0,40"}}},
+			{ID: 4, Name: "fence", X: 0, Y: 200, PolyLines: []PolyLine{{Points: "0,0 100,0"}}},
+			{ID: 5, Name: "spawn", X: 300, Y: 300, Point: &struct{}{}},
+			{ID: 6, Name: "diamond", X: 400, Y: 0, Width: 20, Height: 20, Rotation: 45},
+			{ID: 7, Name: "tile", X: 0, Y: 400, Width: 32, Height: 32, GID: 1},
+		}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(index.Len()).To(Equal(7))
+	})
+
+	It("finds objects containing points", func() {
+		for _, c := range []struct {
+			point    Vertex
+			expected []int
+		}{
+			{Vertex{X: 16, Y: 16}, []int{1}},
+			{Vertex{X: 32, Y: 32}, []int{1}},
+			{Vertex{X: 131, Y: 10}, []int{2}},
+			{Vertex{X: 101, Y: 1}, []int{}},
+			{Vertex{X: 205, Y: 105}, []int{3}},
+			{Vertex{X: 235, Y: 135}, []int{}},
+			{Vertex{X: 50, Y: 200}, []int{4}},
+			{Vertex{X: 50, Y: 201}, []int{}},
+			{Vertex{X: 300, Y: 300}, []int{5}},
+			{Vertex{X: 400, Y: 14}, []int{6}},
+			{Vertex{X: 412, Y: 2}, []int{}},
+			{Vertex{X: 16, Y: 380}, []int{7}},
+			{Vertex{X: 16, Y: 410}, []int{}},
+		} {
+			Expect(ids(index.QueryPoint(c.point))).To(Equal(c.expected), "%v", c.point)
+		}
+	})
+
+	It("finds objects overlapping rectangles", func() {
+		for _, c := range []struct {
+			min, max Vertex
+			expected []int
+		}{
+			{Vertex{X: 150, Y: 15}, Vertex{X: 170, Y: 30}, []int{2}},
+			{Vertex{X: 155, Y: 17}, Vertex{X: 170, Y: 30}, []int{}},
+			{Vertex{X: 40, Y: 190}, Vertex{X: 60, Y: 210}, []int{4}},
+			{Vertex{X: 202, Y: 102}, Vertex{X: 205, Y: 105}, []int{3}},
+			{Vertex{X: 220, Y: 125}, Vertex{X: 240, Y: 140}, []int{}},
+			{Vertex{X: 10, Y: 10}, Vertex{X: 210, Y: 110}, []int{1, 2, 3}},
+			{Vertex{X: -1000, Y: -1000}, Vertex{X: 1000, Y: 1000}, []int{1, 2, 3, 4, 5, 6, 7}},
+		} {
+			Expect(ids(index.QueryRect(c.min, c.max))).To(Equal(c.expected), "%v %v", c.min, c.max)
+		}
+	})
+
+	It("finds objects overlapping circles", func() {
+		for _, c := range []struct {
+			center   Vertex
+			radius   float64
+			expected []int
+		}{
+			{Vertex{X: 16, Y: 16}, 1, []int{1}},
+			{Vertex{X: 60, Y: 16}, 28, []int{1}},
+			{Vertex{X: 60, Y: 16}, 27, []int{}},
+			{Vertex{X: 130, Y: 30}, 10, []int{2}},
+			{Vertex{X: 130, Y: 30}, 9.9, []int{}},
+			{Vertex{X: 303, Y: 304}, 5, []int{5}},
+			{Vertex{X: 303, Y: 304}, 4.9, []int{}},
+		} {
+			Expect(ids(index.QueryRadius(c.center, c.radius))).To(Equal(c.expected), "%v %f", c.center, c.radius)
+		}
+	})
+
+	It("updates moved objects", func() {
+		crate, ok := index.Get(1)
+		Expect(ok).To(BeTrue())
+		crate.X, crate.Y = 1000, 1000
+		Expect(index.Update(crate)).To(Succeed())
+
+		Expect(index.QueryPoint(Vertex{X: 16, Y: 16})).To(BeEmpty())
+		Expect(index.QueryPoint(Vertex{X: 1010, Y: 1010})).To(Equal([]Object{crate}))
+		Expect(index.Len()).To(Equal(7))
+
+		Expect(index.Remove(5)).To(BeTrue())
+		Expect(index.Remove(5)).To(BeFalse())
+		Expect(index.QueryPoint(Vertex{X: 300, Y: 300})).To(BeEmpty())
+		Expect(index.Update(Object{ID: 5})).ToNot(Succeed())
+	})
+
+	It("rejects invalid objects", func() {
+		Expect(index.Insert(Object{ID: 1})).ToNot(Succeed())
+		Expect(index.Insert(Object{Name: "unnamed"})).ToNot(Succeed())
+		Expect(index.Update(Object{ID: 3, Polygons: []Polygon{{Points: "0,0 a"}}})).ToNot(Succeed())
+		Expect(ids(index.QueryPoint(Vertex{X: 205, Y: 105}))).To(Equal([]int{3}))
+
+		_, err := NewObjectIndex(-1)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("indexes objects of maps", func() {
+		f, err := os.Open("testfiles/objects.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		m, err := NewMap(f)
+		Expect(err).ToNot(HaveOccurred())
+
+		index, err := NewObjectIndex(0, m.ObjectGroups...)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(index.Len()).To(Equal(4))
+		Expect(ids(index.QueryPoint(Vertex{X: 2, Y: 3}))).To(Equal([]int{9}))
+	})
+})
@@ -0,0 +1,280 @@
+package tmx
+
+import (
+	"image"
+	"reflect"
+)
+
+//StampOptions configure Stamp
+type StampOptions struct {
+	//Region of the source map in tiles, the whole map is used if empty
+	Region image.Rectangle
+}
+
+//Stamp copies the tiles and objects of src, or a region of it, into m with
+//the top left corner at tile x, y. Tiles and objects are copied to the layers
+//and object groups with the same name including those in groups, missing ones
+//are added to the group of m with the name of their group. Empty tiles
+//of src don't replace tiles of m. Tilesets of src that are used are added to
+//m unless m contains an identical tileset, gids are remapped accordingly.
+//Copied objects get new ids. Paths of images and external tilesets are copied
+//unchanged, so both maps should be located in the same directory.
+func (m *Map) Stamp(src Map, x, y int, opts StampOptions) error {
+	region := image.Rect(0, 0, src.Width, src.Height)
+	if !opts.Region.Empty() {
+		region = opts.Region.Intersect(region)
+	}
+
+	pixels := image.Rect(
+		region.Min.X*src.TileWidth, region.Min.Y*src.TileHeight,
+		region.Max.X*src.TileWidth, region.Max.Y*src.TileHeight,
+	)
+
+	//collect all used tilesets first, m is not changed for invalid gids
+	used := map[*Tileset]bool{}
+	use := func(gid GID) error {
+		tileset, err := src.GetTilesetForGID(gid &^ GIDFlips)
+		if tileset != nil {
+			used[tileset] = true
+		}
+
+		return err
+	}
+
+	if err := src.forEachLayer(func(l *Layer) error {
+		return l.eachTileIn(region, func(_, _ int, tile DataTile) error {
+			return use(tile.GID)
+		})
+	}); err != nil {
+		return err
+	}
+
+	if err := src.forEachObjectGroup(func(g *ObjectGroup) error {
+		for _, o := range g.Objects {
+			if o.isInside(pixels) {
+				if err := use(GID(o.GID)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	firstGIDs := map[*Tileset]GID{}
+	for i := range src.Tilesets {
+		tileset := &src.Tilesets[i]
+		if used[tileset] {
+			firstGIDs[tileset] = m.mergeTileset(*tileset)
+		}
+	}
+
+	remap := func(gid GID) GID {
+		tileset, _ := src.GetTilesetForGID(gid &^ GIDFlips)
+		if tileset == nil {
+			return gid
+		}
+
+		return gid - tileset.FirstGID + firstGIDs[tileset]
+	}
+
+	m.addMissingLayers(src)
+	for _, l := range src.AllLayers() {
+		target := m.GetLayerByName(l.Name)
+		l.eachTileIn(region, func(tx, ty int, tile DataTile) error {
+			if tile.GID != 0 {
+				tile.GID = remap(tile.GID)
+				if i, err := target.index(x+tx-region.Min.X, y+ty-region.Min.Y); err == nil {
+					target.Data.DataTiles[i] = tile
+				}
+			}
+
+			return nil
+		})
+	}
+
+	offsetX := float64(x*m.TileWidth - pixels.Min.X)
+	offsetY := float64(y*m.TileHeight - pixels.Min.Y)
+	return src.forEachObjectGroup(func(g *ObjectGroup) error {
+		target := m.getObjectGroupByName(g.Name)
+		for _, o := range g.Objects {
+			if !o.isInside(pixels) {
+				continue
+			}
+
+			if o.GID != 0 {
+				o.GID = int(remap(GID(o.GID)))
+			}
+
+			o.ID = m.nextObjectID()
+			o.X += offsetX
+			o.Y += offsetY
+			target.Objects = append(target.Objects, o)
+		}
+
+		return nil
+	})
+}
+
+//eachTileIn calls f for every tile of l inside of region
+func (l Layer) eachTileIn(region image.Rectangle, f func(x, y int, tile DataTile) error) error {
+	region = region.Intersect(image.Rect(0, 0, l.Width, l.Height))
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			tile, err := l.TileAt(x, y)
+			if err != nil {
+				continue
+			}
+
+			if err := f(x, y, tile); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//isInside returns true if the position of o is inside of r
+func (o Object) isInside(r image.Rectangle) bool {
+	return o.X >= float64(r.Min.X) && o.X < float64(r.Max.X) &&
+		o.Y >= float64(r.Min.Y) && o.Y < float64(r.Max.Y)
+}
+
+//getObjectGroupByName returns the first object group of m
+//with the given name including those in groups
+func (m *Map) getObjectGroupByName(name string) *ObjectGroup {
+	var found *ObjectGroup
+	m.forEachObjectGroup(func(g *ObjectGroup) error {
+		if found == nil && g.Name == name {
+			found = g
+		}
+
+		return nil
+	})
+
+	return found
+}
+
+//addMissingLayers adds empty copies of the layers and object groups of src
+//that are missing in m. They are added to the group of m with the name of
+//their group, missing groups are created if they contain missing layers.
+func (m *Map) addMissingLayers(src Map) {
+	layers, objectGroups := map[string]bool{}, map[string]bool{}
+	m.forEachLayer(func(l *Layer) error {
+		layers[l.Name] = true
+		return nil
+	})
+	m.forEachObjectGroup(func(g *ObjectGroup) error {
+		objectGroups[g.Name] = true
+		return nil
+	})
+
+	var add func(g *Group, src Group)
+	add = func(g *Group, src Group) {
+		for _, l := range src.Layers {
+			if !layers[l.Name] {
+				layers[l.Name] = true
+				g.Layers = append(g.Layers, Layer{
+					Name:       l.Name,
+					Class:      l.Class,
+					Opacity:    l.Opacity,
+					Visible:    l.Visible,
+					Properties: l.Properties,
+					Width:      m.Width,
+					Height:     m.Height,
+					Data:       Data{DataTiles: make([]DataTile, m.Width*m.Height)},
+				})
+			}
+		}
+
+		for _, o := range src.ObjectGroups {
+			if !objectGroups[o.Name] {
+				objectGroups[o.Name] = true
+				o.Objects = nil
+				o.position = 0
+				g.ObjectGroups = append(g.ObjectGroups, o)
+			}
+		}
+
+		for _, nested := range src.Groups {
+			i := 0
+			for i < len(g.Groups) && g.Groups[i].Name != nested.Name {
+				i++
+			}
+
+			if i < len(g.Groups) {
+				add(&g.Groups[i], nested)
+				continue
+			}
+
+			added := Group{
+				Name:       nested.Name,
+				Class:      nested.Class,
+				Opacity:    nested.Opacity,
+				Visible:    nested.Visible,
+				OffsetX:    nested.OffsetX,
+				OffsetY:    nested.OffsetY,
+				Properties: nested.Properties,
+			}
+			add(&added, nested)
+			if len(added.Layers)+len(added.ObjectGroups)+len(added.Groups) > 0 {
+				g.Groups = append(g.Groups, added)
+			}
+		}
+	}
+
+	root := m.root()
+	add(&root, src.root())
+	m.Layers, m.ObjectGroups, m.Groups = root.Layers, root.ObjectGroups, root.Groups
+}
+
+//nextObjectID returns an unused object id and increments NextObjectID,
+//objects in groups are considered if NextObjectID is unset
+func (m *Map) nextObjectID() int {
+	if m.NextObjectID == 0 {
+		m.NextObjectID = 1
+		m.forEachObjectGroup(func(g *ObjectGroup) error {
+			for _, o := range g.Objects {
+				if o.ID >= m.NextObjectID {
+					m.NextObjectID = o.ID + 1
+				}
+			}
+
+			return nil
+		})
+	}
+
+	id := m.NextObjectID
+	m.NextObjectID++
+	return id
+}
+
+//mergeTileset returns the FirstGID of a tileset of m identical to t,
+//t is added to m if there is none
+func (m *Map) mergeTileset(t Tileset) GID {
+	for _, tileset := range m.Tilesets {
+		if tileset.isIdentical(t) {
+			return tileset.FirstGID
+		}
+	}
+
+	t.Tiles = append([]Tile(nil), t.Tiles...)
+	return m.AddTileset(t)
+}
+
+//isIdentical returns true if t and o are the same external tileset
+//or if they are equal except for their FirstGID
+func (t Tileset) isIdentical(o Tileset) bool {
+	if t.Source != "" || o.Source != "" {
+		return t.Source == o.Source && t.filename == o.filename
+	}
+
+	t.FirstGID, o.FirstGID = 0, 0
+	t.tileIndex, o.tileIndex = nil, nil
+	t.indexedTiles, o.indexedTiles = nil, nil
+
+	return reflect.DeepEqual(t, o)
+}
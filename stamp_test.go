@@ -0,0 +1,177 @@
+package tmx_test
+
+import (
+	"image"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test stamping maps", func() {
+	floor := Tileset{Name: "floor", TileWidth: 16, TileHeight: 16, Image: Image{Source: "floor.png", Width: 64, Height: 64}}
+	walls := Tileset{Name: "walls", TileWidth: 16, TileHeight: 16, Image: Image{Source: "walls.png", Width: 32, Height: 32}}
+
+	gids := func(l *Layer) []GID {
+		result := make([]GID, len(l.Data.DataTiles))
+		for i, tile := range l.Data.DataTiles {
+			result[i] = tile.GID
+		}
+
+		return result
+	}
+
+	var dungeon, room Map
+
+	BeforeEach(func() {
+		dungeon = Map{Width: 4, Height: 3, TileWidth: 16, TileHeight: 16}
+		dungeon.AddTileset(floor)
+		ground := dungeon.AddLayer("Ground")
+		ground.Fill(1, 0)
+		dungeon.ObjectGroups = []ObjectGroup{{Name: "Entities", Objects: []Object{{ID: 7, Name: "start"}}}}
+
+		//the room uses both tilesets in the opposite order
+		room = Map{Width: 2, Height: 2, TileWidth: 16, TileHeight: 16}
+		room.AddTileset(walls)
+		room.AddTileset(floor)
+		ground = room.AddLayer("Ground")
+		Expect(ground.SetTile(0, 0, 6, 0)).To(Succeed())
+		Expect(ground.SetTile(1, 0, 7, GIDHorizontalFlip)).To(Succeed())
+		Expect(ground.SetTile(1, 1, 2, 0)).To(Succeed())
+		room.AddLayer("Walls").Fill(1, 0)
+		room.ObjectGroups = []ObjectGroup{{Name: "Entities", Objects: []Object{
+			{ID: 1, Name: "chest", X: 4, Y: 8, GID: 8 | GIDVerticalFlip},
+			{ID: 2, Name: "torch", X: 20, Y: 30},
+		}}}
+	})
+
+	It("copies tiles and merges identical tilesets", func() {
+		Expect(dungeon.Stamp(room, 1, 1, StampOptions{})).To(Succeed())
+
+		Expect(dungeon.Tilesets).To(HaveLen(2))
+		Expect(dungeon.Tilesets[1].Name).To(Equal("walls"))
+		Expect(dungeon.Tilesets[1].FirstGID).To(Equal(GID(17)))
+
+		Expect(gids(dungeon.GetLayerByName("Ground"))).To(Equal([]GID{
+			1, 1, 1, 1,
+			1, 2, 3, 1,
+			1, 1, 18, 1,
+		}))
+		Expect(dungeon.Layers[0].Data.DataTiles[6].HorizontalFlip).To(BeTrue())
+
+		Expect(gids(dungeon.GetLayerByName("Walls"))).To(Equal([]GID{
+			0, 0, 0, 0,
+			0, 17, 17, 0,
+			0, 17, 17, 0,
+		}))
+
+		tile, err := dungeon.Layers[0].TileAt(1, 1)
+		Expect(err).ToNot(HaveOccurred())
+		tileset, err := dungeon.GetTilesetForGID(tile.GID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tileset.Name).To(Equal("floor"))
+	})
+
+	It("translates objects and assigns new ids", func() {
+		Expect(dungeon.Stamp(room, 1, 1, StampOptions{})).To(Succeed())
+		Expect(dungeon.Stamp(room, 2, 0, StampOptions{})).To(Succeed())
+
+		objects := dungeon.ObjectGroups[0].Objects
+		Expect(objects).To(HaveLen(5))
+		Expect(objects[1]).To(Equal(Object{ID: 8, Name: "chest", X: 20, Y: 24, GID: 4 | GIDVerticalFlip}))
+		Expect(objects[2]).To(Equal(Object{ID: 9, Name: "torch", X: 36, Y: 46}))
+		Expect(objects[3].ID).To(Equal(10))
+		Expect(objects[3].X).To(Equal(36.0))
+		Expect(dungeon.NextObjectID).To(Equal(12))
+	})
+
+	It("assigns ids that are not used by objects in groups", func() {
+		dungeon.Groups = []Group{{Groups: []Group{{ObjectGroups: []ObjectGroup{{Objects: []Object{{ID: 20}}}}}}}}
+		Expect(dungeon.Stamp(room, 1, 1, StampOptions{})).To(Succeed())
+
+		objects := dungeon.ObjectGroups[0].Objects
+		Expect(objects[1].ID).To(Equal(21))
+		Expect(objects[2].ID).To(Equal(22))
+	})
+
+	It("copies layers of groups into groups with the same name", func() {
+		room.Groups = []Group{{Name: "Upper", Opacity: 0.5, Layers: []Layer{room.Layers[1]}, ObjectGroups: []ObjectGroup{
+			{Name: "Lamps", Objects: []Object{{ID: 3, Name: "lamp", X: 4, Y: 4}}},
+		}}}
+		room.Layers = room.Layers[:1]
+		Expect(dungeon.Stamp(room, 1, 1, StampOptions{})).To(Succeed())
+
+		Expect(dungeon.Layers).To(HaveLen(1))
+		Expect(dungeon.Groups).To(HaveLen(1))
+		upper := dungeon.Groups[0]
+		Expect(upper.Name).To(Equal("Upper"))
+		Expect(upper.Opacity).To(Equal(float32(0.5)))
+		Expect(gids(&upper.Layers[0])).To(Equal([]GID{
+			0, 0, 0, 0,
+			0, 17, 17, 0,
+			0, 17, 17, 0,
+		}))
+		Expect(upper.ObjectGroups[0].Objects).To(Equal([]Object{{ID: 10, Name: "lamp", X: 20, Y: 20}}))
+	})
+
+	It("copies tiles into grouped layers with the same name", func() {
+		dungeon.Groups = []Group{{Name: "Structure", Layers: []Layer{
+			{Name: "Walls", Width: 4, Height: 3, Data: Data{DataTiles: make([]DataTile, 12)}},
+		}}}
+		Expect(dungeon.Stamp(room, 1, 1, StampOptions{})).To(Succeed())
+
+		Expect(dungeon.Layers).To(HaveLen(1))
+		Expect(gids(&dungeon.Groups[0].Layers[0])).To(Equal([]GID{
+			0, 0, 0, 0,
+			0, 17, 17, 0,
+			0, 17, 17, 0,
+		}))
+	})
+
+	It("copies regions", func() {
+		Expect(dungeon.Stamp(room, 0, 0, StampOptions{Region: image.Rect(1, 1, 5, 5)})).To(Succeed())
+
+		Expect(dungeon.Tilesets).To(HaveLen(2))
+		Expect(gids(dungeon.GetLayerByName("Ground"))[0]).To(Equal(GID(18)))
+		Expect(gids(dungeon.GetLayerByName("Walls"))).To(Equal([]GID{
+			17, 0, 0, 0,
+			0, 0, 0, 0,
+			0, 0, 0, 0,
+		}))
+
+		objects := dungeon.ObjectGroups[0].Objects
+		Expect(objects).To(HaveLen(2))
+		Expect(objects[1].Name).To(Equal("torch"))
+		Expect(objects[1].X).To(Equal(4.0))
+		Expect(objects[1].Y).To(Equal(14.0))
+	})
+
+	It("only adds used tilesets", func() {
+		Expect(dungeon.Stamp(room, 0, 0, StampOptions{Region: image.Rect(1, 1, 2, 2)})).To(Succeed())
+		Expect(dungeon.Tilesets).To(HaveLen(2))
+
+		room.Layers = room.Layers[:1]
+		room.ObjectGroups = nil
+		empty := Map{Width: 2, Height: 2, TileWidth: 16, TileHeight: 16}
+		Expect(empty.Stamp(room, 0, 0, StampOptions{Region: image.Rect(1, 1, 2, 2)})).To(Succeed())
+		Expect(empty.Tilesets).To(HaveLen(1))
+		Expect(empty.Tilesets[0].Name).To(Equal("walls"))
+		Expect(gids(&empty.Layers[0])).To(Equal([]GID{2, 0, 0, 0}))
+	})
+
+	It("fails for invalid gids without changing the map", func() {
+		Expect(room.Layers[0].SetTile(0, 1, 500, 0)).To(Succeed())
+		Expect(dungeon.Stamp(room, 0, 0, StampOptions{})).ToNot(Succeed())
+		Expect(dungeon.Tilesets).To(HaveLen(1))
+		Expect(dungeon.Layers).To(HaveLen(1))
+		Expect(gids(&dungeon.Layers[0])).To(Equal([]GID{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
+	})
+
+	It("merges identical external tilesets", func() {
+		filename := "testfiles/external/external_example.tmx"
+		target := mustLoadTestMap(filename)
+		Expect(target.Stamp(*mustLoadTestMap(filename), 0, 0, StampOptions{})).To(Succeed())
+		Expect(target.Tilesets).To(HaveLen(1))
+		Expect(target.ObjectGroups[0].Objects).To(HaveLen(2 * len(mustLoadTestMap(filename).ObjectGroups[0].Objects)))
+	})
+})
@@ -0,0 +1,9 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.2" name="chipset" tilewidth="32" tileheight="32" tilecount="500" columns="10">
+ <image source="../chipset.png" width="320" height="1600"/>
+ <tile id="32">
+  <properties>
+   <property name="sound" value="on"/>
+  </properties>
+ </tile>
+</tileset>
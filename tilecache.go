@@ -0,0 +1,155 @@
+package tmx
+
+import (
+	"container/list"
+	"image"
+	"image/color"
+	"sync"
+)
+
+//tileKey identifies one sliced and flipped tile
+type tileKey struct {
+	tileset string
+	trans   string
+	tileID  int
+	bounds  image.Rectangle
+	flips   GID
+}
+
+type tileEntry struct {
+	key   tileKey
+	tile  image.Image
+	bytes int64
+}
+
+//TileCache stores tiles that have already been sliced from
+//their tileset and flipped so they can be drawn directly.
+//It is safe for concurrent use.
+type TileCache struct {
+	mutex    sync.Mutex
+	maxBytes int64
+	bytes    int64
+	entries  map[tileKey]*list.Element
+	order    *list.List
+}
+
+//NewTileCache creates a TileCache that evicts the least recently
+//used tiles once more than maxBytes are stored,
+//a maxBytes of 0 means the cache is unbounded
+func NewTileCache(maxBytes int64) *TileCache {
+	return &TileCache{
+		maxBytes: maxBytes,
+		entries:  map[tileKey]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+//Len returns the number of cached tiles
+func (c *TileCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.order.Len()
+}
+
+//Bytes returns the estimated memory used by all cached tiles
+func (c *TileCache) Bytes() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.bytes
+}
+
+//Clear removes all cached tiles
+func (c *TileCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = map[tileKey]*list.Element{}
+	c.order.Init()
+	c.bytes = 0
+}
+
+func (c *TileCache) get(key tileKey) (image.Image, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return element.Value.(*tileEntry).tile, true
+}
+
+//put stores a copy of tile that can be drawn fast and returns it
+func (c *TileCache) put(key tileKey, tile image.Image) image.Image {
+	tile = compactTile(tile)
+	bounds := tile.Bounds()
+	entry := &tileEntry{key: key, tile: tile, bytes: int64(bounds.Dx() * bounds.Dy() * 4)}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+
+	if c.maxBytes > 0 && entry.bytes > c.maxBytes {
+		return tile
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	c.bytes += entry.bytes
+
+	for c.maxBytes > 0 && c.bytes > c.maxBytes {
+		c.removeElement(c.order.Back())
+	}
+
+	return tile
+}
+
+func (c *TileCache) removeElement(element *list.Element) {
+	entry := c.order.Remove(element).(*tileEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.bytes
+}
+
+//compactTile copies paletted tiles to NRGBA images which draw
+//much faster. Only palettes that convert without loss are copied,
+//so the drawn result is exactly the same.
+func compactTile(tile image.Image) image.Image {
+	paletted, ok := tile.(*image.Paletted)
+	if !ok {
+		return tile
+	}
+
+	for _, c := range paletted.Palette {
+		switch c := c.(type) {
+		case color.NRGBA:
+		case color.RGBA:
+			if c.A != 0xff {
+				return tile
+			}
+		default:
+			return tile
+		}
+	}
+
+	bounds := paletted.Bounds()
+	target := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			index := paletted.ColorIndexAt(x, y)
+			if int(index) >= len(paletted.Palette) {
+				return tile
+			}
+
+			target.SetNRGBA(x, y, color.NRGBAModel.Convert(paletted.Palette[index]).(color.NRGBA))
+		}
+	}
+
+	return target
+}
@@ -0,0 +1,88 @@
+package tmx_test
+
+import (
+	"testing"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test tile cache", func() {
+	var testMap *Map
+
+	BeforeEach(func() {
+		var err error
+		testMap, err = loadTestMap("./testfiles/simple_example.tmx")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	render := func(cache *TileCache) *ImgCanvas {
+		c := NewImageCanvasFromMap(*testMap)
+		renderer := NewRendererWithOptions(*testMap, c, RenderOptions{TileCache: cache})
+		Expect(renderer.Render(0)).To(Succeed())
+
+		return c
+	}
+
+	It("renders the same image with a cache", func() {
+		expected := render(nil)
+		cache := NewTileCache(0)
+		Expect(render(cache).Image()).To(EqualImage(expected.Image()))
+		Expect(cache.Len()).To(BeNumerically(">", 0))
+		Expect(render(cache).Image()).To(EqualImage(expected.Image()))
+	})
+
+	It("reuses the cache between renders", func() {
+		cache := NewTileCache(0)
+		render(cache)
+		length := cache.Len()
+		bytes := cache.Bytes()
+		render(cache)
+		Expect(cache.Len()).To(Equal(length))
+		Expect(cache.Bytes()).To(Equal(bytes))
+	})
+
+	It("stays within its memory bounds", func() {
+		maxBytes := int64(3 * 32 * 32 * 4)
+		cache := NewTileCache(maxBytes)
+		expected := render(nil)
+		Expect(render(cache).Image()).To(EqualImage(expected.Image()))
+		Expect(cache.Len()).To(Equal(3))
+		Expect(cache.Bytes()).To(BeNumerically("<=", maxBytes))
+	})
+
+	It("can be cleared", func() {
+		cache := NewTileCache(0)
+		render(cache)
+		cache.Clear()
+		Expect(cache.Len()).To(Equal(0))
+		Expect(cache.Bytes()).To(Equal(int64(0)))
+	})
+})
+
+func benchmarkRender(b *testing.B, cache *TileCache) {
+	testMap, err := loadTestMap("./testfiles/simple_example.tmx")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	c := NewImageCanvasFromMap(*testMap)
+	renderer := NewRendererWithOptions(*testMap, c, RenderOptions{TileCache: cache})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := renderer.Render(0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderWithoutTileCache(b *testing.B) {
+	benchmarkRender(b, nil)
+}
+
+func BenchmarkRenderWithTileCache(b *testing.B) {
+	benchmarkRender(b, NewTileCache(0))
+}
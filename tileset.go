@@ -2,29 +2,71 @@ package tmx
 
 // Tileset entry describes a complete tileset
 type Tileset struct {
-	FirstGID   GID        `xml:"firstgid,attr"`
-	Source     string     `xml:"source,attr"`
-	Name       string     `xml:"name,attr"`
-	TileWidth  int        `xml:"tilewidth,attr"`
-	TileHeight int        `xml:"tileheight,attr"`
-	Spacing    int        `xml:"spacing,attr"`
-	Margin     int        `xml:"margin,attr"`
-	Properties []Property `xml:"properties>property"`
-	Image      Image      `xml:"image"`
-	Tiles      []Tile     `xml:"tile"`
-}
-
-//GetTileByID returns special tile information
+	FirstGID   GID         `xml:"firstgid,attr"`
+	Source     string      `xml:"source,attr,omitempty"`
+	Name       string      `xml:"name,attr"`
+	Class      string      `xml:"class,attr,omitempty"`
+	TileWidth  int         `xml:"tilewidth,attr"`
+	TileHeight int         `xml:"tileheight,attr"`
+	Spacing    int         `xml:"spacing,attr,omitempty"`
+	Margin     int         `xml:"margin,attr,omitempty"`
+	Properties Properties  `xml:"properties,omitempty"`
+	TileOffset *TileOffset `xml:"tileoffset"`
+	//Transformations allowed for tiles of wang sets
+	Transformations *Transformations `xml:"transformations"`
+	Image           Image            `xml:"image"`
+	//TerrainTypes are only used by files of Tiled 1.4 and earlier,
+	//GetWangSets converts them to a wang set
+	TerrainTypes []Terrain `xml:"terraintypes>terrain"`
+	Tiles        []Tile    `xml:"tile"`
+	WangSets     []WangSet `xml:"wangsets>wangset"`
+	//filename is the resolved path of an external tileset
+	filename string
+	//tileIndex maps tile ids to their index in indexedTiles
+	tileIndex    map[uint32]int
+	indexedTiles []Tile
+}
+
+//buildIndex speeds up GetTileByID
+func (t *Tileset) buildIndex() {
+	t.indexedTiles = t.Tiles
+	t.tileIndex = make(map[uint32]int, len(t.Tiles))
+	for i, tile := range t.Tiles {
+		if _, ok := t.tileIndex[tile.ID]; !ok {
+			t.tileIndex[tile.ID] = i
+		}
+	}
+}
+
+//GetTileByID returns special tile information,
+//changes to the returned tile are stored in the tileset
 func (t Tileset) GetTileByID(tileID uint32) *Tile {
-	for _, t := range t.Tiles {
-		if t.ID == tileID {
-			return &t
+	//ids may have been changed since the index was built, so
+	//only found tiles are trusted and all others are searched
+	if t.isIndexed() {
+		if i, ok := t.tileIndex[tileID]; ok && t.Tiles[i].ID == tileID {
+			return &t.Tiles[i]
+		}
+	}
+
+	for i := range t.Tiles {
+		if t.Tiles[i].ID == tileID {
+			return &t.Tiles[i]
 		}
 	}
 
 	return nil
 }
 
+//isIndexed returns true if the index was built for the current tiles
+func (t Tileset) isIndexed() bool {
+	if t.tileIndex == nil || len(t.indexedTiles) != len(t.Tiles) {
+		return false
+	}
+
+	return len(t.Tiles) == 0 || &t.indexedTiles[0] == &t.Tiles[0]
+}
+
 //GetFilename returns the filename for this tileset
 func (t Tileset) GetFilename() string {
 	if t.Source != "" {
@@ -34,34 +76,122 @@ func (t Tileset) GetFilename() string {
 	return t.Image.Source
 }
 
+//IsCollection returns true if every tile of this tileset
+//has its own image instead of sharing one tileset image
+func (t Tileset) IsCollection() bool {
+	return t.Image.Source == "" && len(t.Tiles) > 0
+}
+
 //GetNumTiles returns the number of tiles of this tileset
+//for collections of images this is the highest tile id + 1
 func (t Tileset) GetNumTiles() int {
+	if t.IsCollection() {
+		numTiles := 0
+		for _, tile := range t.Tiles {
+			if int(tile.ID) >= numTiles {
+				numTiles = int(tile.ID) + 1
+			}
+		}
+
+		return numTiles
+	}
+
 	return t.GetNumTilesX() * t.GetNumTilesY()
 }
 
+//containsGID returns true if gid belongs to a tile of t, it is cheap
+//for existing tiles of collections whose size is expensive to count
+func (t Tileset) containsGID(gid GID) bool {
+	if gid < t.FirstGID {
+		return false
+	}
+
+	id := gid - t.FirstGID
+	if t.IsCollection() && t.GetTileByID(uint32(id)) != nil {
+		return true
+	}
+
+	return int(id) < t.GetNumTiles()
+}
+
 //GetNumTilesX returns the number of tiles in x direction
 func (t Tileset) GetNumTilesX() int {
+	if t.TileWidth == 0 {
+		return 0
+	}
+
 	return t.Image.Width / t.TileWidth
 }
 
 //GetNumTilesY returns the number of tiles in y direction
 func (t Tileset) GetNumTilesY() int {
+	if t.TileHeight == 0 {
+		return 0
+	}
+
 	return t.Image.Height / t.TileHeight
 }
 
 // Image refers to the image of one tile or the tileset
 type Image struct {
 	Source string `xml:"source,attr"`
-	Trans  string `xml:"trans,attr"`
-	Width  int    `xml:"width,attr"`
-	Height int    `xml:"height,attr"`
+	Trans  string `xml:"trans,attr,omitempty"`
+	Width  int    `xml:"width,attr,omitempty"`
+	Height int    `xml:"height,attr,omitempty"`
+}
+
+//TileOffset moves all tiles of a tileset when they are drawn
+type TileOffset struct {
+	X int `xml:"x,attr" json:"x"`
+	Y int `xml:"y,attr" json:"y"`
+}
+
+//Transformations lists how tiles may be flipped or rotated by terrain tools
+type Transformations struct {
+	HFlip  bool `xml:"hflip,attr" json:"hflip"`
+	VFlip  bool `xml:"vflip,attr" json:"vflip"`
+	Rotate bool `xml:"rotate,attr" json:"rotate"`
+	//PreferUntransformed uses transformed tiles only if
+	//there is no untransformed tile that matches
+	PreferUntransformed bool `xml:"preferuntransformed,attr" json:"preferuntransformed"`
 }
 
 // Tile refers to one tile in the tileset
 type Tile struct {
-	ID        uint32     `xml:"id,attr"`
-	Image     Image      `xml:"image"`
-	Animation *Animation `xml:"animation"`
+	ID uint32 `xml:"id,attr"`
+	//Type is the class of the tile in files of Tiled 1.8 and earlier
+	//and of Tiled 1.10 and later, use GetClass to read either
+	Type  string `xml:"type,attr,omitempty"`
+	Class string `xml:"class,attr,omitempty"`
+	//Probability of the tile to be chosen by terrain and
+	//random tools, use GetProbability to apply the default
+	Probability *float64   `xml:"probability,attr"`
+	Properties  Properties `xml:"properties,omitempty"`
+	//Terrain contains the indices of the terrain types at the top left, top
+	//right, bottom left and bottom right corner, used by Tiled 1.4 and earlier
+	Terrain string `xml:"terrain,attr,omitempty"`
+	Image   Image  `xml:"image"`
+	//ObjectGroup contains the collision shapes of the tile
+	ObjectGroup *ObjectGroup `xml:"objectgroup"`
+	Animation   *Animation   `xml:"animation"`
+}
+
+//GetClass returns the class of the tile
+func (t Tile) GetClass() string {
+	if t.Class != "" {
+		return t.Class
+	}
+
+	return t.Type
+}
+
+//GetProbability returns the probability of the tile, it defaults to 1
+func (t Tile) GetProbability() float64 {
+	if t.Probability == nil {
+		return 1
+	}
+
+	return *t.Probability
 }
 
 //Animation references an animated tile
@@ -106,7 +236,7 @@ func (a *Animation) Update(elapsedTime int64) {
 //Frame is one frame of an animation
 type Frame struct {
 	//Duration is given in milliseconds
-	Duration int64 `xml:"duration,attr"`
-	TileID   int   `xml:"tileid,attr"`
+	Duration int64 `xml:"duration,attr" json:"duration"`
+	TileID   int   `xml:"tileid,attr" json:"tileid"`
 	endTime  int64
 }
@@ -0,0 +1,265 @@
+package tmx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//WangPosition is an index of a WangID, positions
+//start at the top edge and continue clockwise
+type WangPosition int
+
+const (
+	//WangTop is the top edge
+	WangTop WangPosition = iota
+	//WangTopRight is the top right corner
+	WangTopRight
+	//WangRight is the right edge
+	WangRight
+	//WangBottomRight is the bottom right corner
+	WangBottomRight
+	//WangBottom is the bottom edge
+	WangBottom
+	//WangBottomLeft is the bottom left corner
+	WangBottomLeft
+	//WangLeft is the left edge
+	WangLeft
+	//WangTopLeft is the top left corner
+	WangTopLeft
+)
+
+//WangID contains the color of every edge and corner of a tile,
+//colors are indices of WangSet.Colors starting at 1, 0 is no color
+type WangID [8]uint8
+
+//Matches returns true if all colors of pattern are equal
+//to the colors of w, 0 in pattern matches every color
+func (w WangID) Matches(pattern WangID) bool {
+	for i, color := range pattern {
+		if color != 0 && w[i] != color {
+			return false
+		}
+	}
+
+	return true
+}
+
+//UnmarshalXMLAttr reads comma separated colors or the
+//hexadecimal format of Tiled 1.4 and earlier
+func (w *WangID) UnmarshalXMLAttr(attr xml.Attr) error {
+	*w = WangID{}
+	if strings.HasPrefix(attr.Value, "0x") {
+		id, err := strconv.ParseUint(attr.Value[2:], 16, 32)
+		if err != nil {
+			return err
+		}
+
+		for i := range w {
+			w[i] = uint8(id >> (uint(i) * 4) & 0xf)
+		}
+
+		return nil
+	}
+
+	colors := strings.Split(attr.Value, ",")
+	if len(colors) != len(w) {
+		return fmt.Errorf("invalid wangid %s", attr.Value)
+	}
+
+	for i, color := range colors {
+		value, err := strconv.ParseUint(strings.TrimSpace(color), 10, 8)
+		if err != nil {
+			return err
+		}
+
+		w[i] = uint8(value)
+	}
+
+	return nil
+}
+
+//MarshalXMLAttr writes comma separated colors
+func (w WangID) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	colors := make([]string, len(w))
+	for i, color := range w {
+		colors[i] = strconv.Itoa(int(color))
+	}
+
+	return xml.Attr{Name: name, Value: strings.Join(colors, ",")}, nil
+}
+
+//WangSet defines terrains of a tileset by the colors of the
+//corners and edges of its tiles
+type WangSet struct {
+	Name  string `xml:"name,attr" json:"name"`
+	Class string `xml:"class,attr,omitempty" json:"class,omitempty"`
+	//Type is either corner, edge or mixed
+	Type string `xml:"type,attr,omitempty" json:"type,omitempty"`
+	//Tile is the id of the tile representing the set, -1 for none
+	Tile       int         `xml:"tile,attr" json:"tile"`
+	Properties Properties  `xml:"properties,omitempty" json:"properties,omitempty"`
+	Colors     []WangColor `xml:"wangcolor" json:"colors,omitempty"`
+	Tiles      []WangTile  `xml:"wangtile" json:"wangtiles,omitempty"`
+}
+
+//WangColor is one terrain of a wang set
+type WangColor struct {
+	Name  string `xml:"name,attr" json:"name"`
+	Class string `xml:"class,attr,omitempty" json:"class,omitempty"`
+	Color string `xml:"color,attr" json:"color"`
+	//Tile is the id of the tile representing the color, -1 for none
+	Tile int `xml:"tile,attr" json:"tile"`
+	//Probability of the color to be chosen, use GetProbability to apply the default
+	Probability *float64   `xml:"probability,attr" json:"probability,omitempty"`
+	Properties  Properties `xml:"properties,omitempty" json:"properties,omitempty"`
+}
+
+//GetProbability returns the probability of the color, it defaults to 1
+func (c WangColor) GetProbability() float64 {
+	if c.Probability == nil {
+		return 1
+	}
+
+	return *c.Probability
+}
+
+//WangTile assigns the colors of a WangID to a tile of the tileset
+type WangTile struct {
+	TileID uint32 `xml:"tileid,attr" json:"tileid"`
+	WangID WangID `xml:"wangid,attr" json:"wangid"`
+}
+
+//GetColor returns the color with the given index starting at 1,
+//nil is returned for 0 and unknown colors
+func (w WangSet) GetColor(color uint8) *WangColor {
+	if color == 0 || int(color) > len(w.Colors) {
+		return nil
+	}
+
+	return &w.Colors[color-1]
+}
+
+//GetWangID returns the WangID of the tile with the given id
+func (w WangSet) GetWangID(tileID uint32) (WangID, bool) {
+	for _, tile := range w.Tiles {
+		if tile.TileID == tileID {
+			return tile.WangID, true
+		}
+	}
+
+	return WangID{}, false
+}
+
+//ColorAt returns the color at the given corner or edge of
+//the tile with the given id, nil is returned if there is none
+func (w WangSet) ColorAt(tileID uint32, position WangPosition) *WangColor {
+	id, ok := w.GetWangID(tileID)
+	if !ok || position < WangTop || position > WangTopLeft {
+		return nil
+	}
+
+	return w.GetColor(id[position])
+}
+
+//TilesMatching returns all tiles whose colors match pattern
+func (w WangSet) TilesMatching(pattern WangID) []WangTile {
+	var result []WangTile
+	for _, tile := range w.Tiles {
+		if tile.WangID.Matches(pattern) {
+			result = append(result, tile)
+		}
+	}
+
+	return result
+}
+
+//Terrain is a terrain type of Tiled 1.4 and earlier, it
+//was replaced by wang sets
+type Terrain struct {
+	Name string `xml:"name,attr" json:"name"`
+	//Tile is the id of the tile representing the terrain, -1 for none
+	Tile       int        `xml:"tile,attr" json:"tile"`
+	Properties Properties `xml:"properties,omitempty" json:"properties,omitempty"`
+}
+
+//terrainCorners are the positions of the corners in the terrain attribute
+var terrainCorners = []WangPosition{WangTopLeft, WangTopRight, WangBottomLeft, WangBottomRight}
+
+//terrainWangID converts the terrain attribute of a tile,
+//the indices of its corners, to a WangID
+func terrainWangID(terrain string) (WangID, error) {
+	var id WangID
+	corners := strings.Split(terrain, ",")
+	if len(corners) != len(terrainCorners) {
+		return id, fmt.Errorf("invalid terrain %s", terrain)
+	}
+
+	for i, corner := range corners {
+		if corner == "" {
+			continue
+		}
+
+		index, err := strconv.ParseUint(corner, 10, 8)
+		if err != nil || index >= 0xff {
+			return id, fmt.Errorf("invalid terrain %s", terrain)
+		}
+
+		id[terrainCorners[i]] = uint8(index + 1)
+	}
+
+	return id, nil
+}
+
+//GetWangSet returns the wang set with the given name,
+//legacy terrains are searched as well
+func (t Tileset) GetWangSet(name string) (*WangSet, error) {
+	sets, err := t.GetWangSets()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sets {
+		if sets[i].Name == name {
+			return &sets[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+//GetWangSets returns the wang sets of the tileset. Legacy terrains are
+//converted to an additional corner set named like the tileset, with one
+//color for every terrain.
+func (t Tileset) GetWangSets() ([]WangSet, error) {
+	if len(t.TerrainTypes) == 0 {
+		return t.WangSets, nil
+	}
+
+	terrains := WangSet{Name: t.Name, Type: "corner", Tile: -1}
+	for _, terrain := range t.TerrainTypes {
+		terrains.Colors = append(terrains.Colors, WangColor{
+			Name:       terrain.Name,
+			Tile:       terrain.Tile,
+			Properties: terrain.Properties,
+		})
+	}
+
+	for _, tile := range t.Tiles {
+		if tile.Terrain == "" {
+			continue
+		}
+
+		id, err := terrainWangID(tile.Terrain)
+		if err != nil {
+			return nil, err
+		}
+
+		terrains.Tiles = append(terrains.Tiles, WangTile{TileID: tile.ID, WangID: id})
+	}
+
+	sets := make([]WangSet, 0, len(t.WangSets)+1)
+	sets = append(sets, t.WangSets...)
+	return append(sets, terrains), nil
+}
@@ -0,0 +1,132 @@
+package tmx_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test wang sets and terrains", func() {
+	var m *Map
+
+	BeforeEach(func() {
+		f, err := os.Open("testfiles/wang.tmx")
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		m, err = NewMap(f)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("loads wang sets", func() {
+		sets := m.Tilesets[0].WangSets
+		Expect(sets).To(HaveLen(2))
+		Expect(sets[0].Name).To(Equal("Ground"))
+		Expect(sets[0].Type).To(Equal("corner"))
+		Expect(sets[0].Properties).To(Equal(Properties{{Name: "generator", Value: "caves"}}))
+		Expect(sets[0].Colors).To(HaveLen(2))
+		Expect(sets[0].Colors[1].Class).To(Equal("Soil"))
+		Expect(sets[0].Colors[1].GetProbability()).To(Equal(0.25))
+		Expect(sets[0].Colors[1].Properties).To(Equal(Properties{{Name: "walkable", Type: "bool", Value: "true"}}))
+		Expect(sets[0].Tiles[2]).To(Equal(WangTile{TileID: 2, WangID: WangID{0, 1, 0, 2, 0, 2, 0, 1}}))
+		Expect(sets[1].Type).To(Equal("edge"))
+		Expect(sets[1].Tile).To(Equal(-1))
+	})
+
+	It("returns the colors of tiles", func() {
+		set, err := m.Tilesets[0].GetWangSet("Ground")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(set.ColorAt(2, WangTopLeft).Name).To(Equal("Grass"))
+		Expect(set.ColorAt(2, WangBottomRight).Name).To(Equal("Dirt"))
+		Expect(set.ColorAt(2, WangTop)).To(BeNil())
+		Expect(set.ColorAt(5, WangTopLeft)).To(BeNil())
+		Expect(set.ColorAt(2, WangPosition(8))).To(BeNil())
+
+		id, ok := set.GetWangID(3)
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal(WangID{0, 2, 0, 1, 0, 1, 0, 2}))
+	})
+
+	It("finds tiles matching a pattern", func() {
+		set, err := m.Tilesets[0].GetWangSet("Ground")
+		Expect(err).ToNot(HaveOccurred())
+
+		var pattern WangID
+		pattern[WangTopLeft] = 1
+		pattern[WangTopRight] = 1
+		var ids []uint32
+		for _, tile := range set.TilesMatching(pattern) {
+			ids = append(ids, tile.TileID)
+		}
+		Expect(ids).To(Equal([]uint32{0, 2}))
+
+		Expect(set.TilesMatching(WangID{})).To(HaveLen(4))
+		Expect(set.TilesMatching(WangID{1})).To(BeEmpty())
+
+		paths, err := m.Tilesets[0].GetWangSet("Paths")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths.TilesMatching(WangID{WangRight: 1})).To(Equal([]WangTile{
+			{TileID: 11, WangID: WangID{0, 0, 1, 0, 0, 0, 1, 0}},
+		}))
+	})
+
+	It("converts legacy terrains to a corner wang set", func() {
+		tileset := m.Tilesets[1]
+		Expect(tileset.WangSets).To(BeEmpty())
+		Expect(tileset.TerrainTypes).To(HaveLen(2))
+		Expect(tileset.Tiles[2].Terrain).To(Equal(",1,,1"))
+
+		sets, err := tileset.GetWangSets()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sets).To(HaveLen(1))
+		Expect(sets[0].Name).To(Equal("legacy"))
+		Expect(sets[0].Type).To(Equal("corner"))
+		Expect(sets[0].Colors[0].Name).To(Equal("Water"))
+		Expect(sets[0].Colors[0].Tile).To(Equal(3))
+		Expect(sets[0].Colors[1].Properties).To(Equal(Properties{{Name: "slow", Type: "bool", Value: "true"}}))
+		Expect(sets[0].Tiles).To(Equal([]WangTile{
+			{TileID: 0, WangID: WangID{0, 1, 0, 1, 0, 1, 0, 1}},
+			{TileID: 1, WangID: WangID{0, 1, 0, 2, 0, 2, 0, 1}},
+			{TileID: 2, WangID: WangID{0, 2, 0, 2, 0, 0, 0, 0}},
+		}))
+
+		set, err := tileset.GetWangSet("legacy")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(set.ColorAt(1, WangBottomLeft).Name).To(Equal("Sand"))
+
+		tileset.Tiles[0].Terrain = "0,0"
+		_, err = tileset.GetWangSets()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reads wang ids of Tiled 1.4", func() {
+		var id WangID
+		Expect(id.UnmarshalXMLAttr(xml.Attr{Value: "0x21020102"})).To(Succeed())
+		Expect(id).To(Equal(WangID{2, 0, 1, 0, 2, 0, 1, 2}))
+
+		Expect(id.UnmarshalXMLAttr(xml.Attr{Value: "1,2,3"})).ToNot(Succeed())
+	})
+
+	It("keeps wang sets and terrains when encoding", func() {
+		var buffer bytes.Buffer
+		Expect(m.Encode(&buffer, EncodeOptions{})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`wangid="0,1,0,2,0,2,0,1"`))
+		decoded, err := NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Tilesets[0].WangSets).To(Equal(m.Tilesets[0].WangSets))
+		Expect(decoded.Tilesets[1].TerrainTypes).To(Equal(m.Tilesets[1].TerrainTypes))
+		Expect(decoded.Tilesets[1].Tiles).To(Equal(m.Tilesets[1].Tiles))
+
+		buffer.Reset()
+		Expect(m.EncodeJSON(&buffer, EncodeOptions{})).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring(`"terrain": [`))
+		decoded, err = NewMap(&buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded.Tilesets[0].WangSets).To(Equal(m.Tilesets[0].WangSets))
+		Expect(decoded.Tilesets[1].TerrainTypes).To(Equal(m.Tilesets[1].TerrainTypes))
+		Expect(decoded.Tilesets[1].Tiles).To(Equal(m.Tilesets[1].Tiles))
+	})
+})
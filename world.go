@@ -0,0 +1,386 @@
+package tmx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+//World arranges multiple maps as described by a Tiled .world file,
+//maps are only loaded when they are accessed
+type World struct {
+	Maps                 []*WorldMap
+	OnlyShowAdjacentMaps bool
+	//filename is the slash separated path of the world file
+	filename string
+	files    worldFiles
+	opts     LoadOptions
+}
+
+//WorldMap is one map of a world
+type WorldMap struct {
+	//FileName of the map relative to the world file
+	FileName string
+	//X, Y, Width and Height of the map in world pixels
+	X      int
+	Y      int
+	Width  int
+	Height int
+
+	world *World
+	mutex sync.Mutex
+	m     *Map
+}
+
+//worldFile is the JSON content of a .world file
+type worldFile struct {
+	Type string `json:"type"`
+	Maps []struct {
+		FileName string `json:"fileName"`
+		X        int    `json:"x"`
+		Y        int    `json:"y"`
+		Width    int    `json:"width"`
+		Height   int    `json:"height"`
+	} `json:"maps"`
+	Patterns             []worldPattern `json:"patterns"`
+	OnlyShowAdjacentMaps bool           `json:"onlyShowAdjacentMaps"`
+}
+
+//worldPattern places all maps in the directory of the world
+//whose name matches Regexp, the first two submatches are
+//multiplied to get the position of the map
+type worldPattern struct {
+	Regexp      string `json:"regexp"`
+	MultiplierX *int   `json:"multiplierX"`
+	MultiplierY *int   `json:"multiplierY"`
+	OffsetX     int    `json:"offsetX"`
+	OffsetY     int    `json:"offsetY"`
+	MapWidth    *int   `json:"mapWidth"`
+	MapHeight   *int   `json:"mapHeight"`
+}
+
+//worldFiles opens the world file and its maps
+type worldFiles interface {
+	externalFiles
+	readDir(name string) ([]fs.DirEntry, error)
+	loadMap(name string, opts LoadOptions) (*Map, error)
+	locator() ResourceLocator
+}
+
+func (o osFiles) readDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.FromSlash(name))
+}
+
+func (o osFiles) loadMap(name string, opts LoadOptions) (*Map, error) {
+	f, err := o.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewMapWithOptions(f, opts)
+}
+
+func (o osFiles) locator() ResourceLocator {
+	return FilesystemLocator{}
+}
+
+func (f fsFiles) readDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fsys, fsPath(name))
+}
+
+func (f fsFiles) loadMap(name string, opts LoadOptions) (*Map, error) {
+	return LoadMapFSWithOptions(f.fsys, fsPath(name), opts)
+}
+
+func (f fsFiles) locator() ResourceLocator {
+	return FSLocator{FS: f.fsys}
+}
+
+//LoadWorld loads the world file stored at filename,
+//maps are loaded relative to the world file
+func LoadWorld(filename string) (*World, error) {
+	return LoadWorldWithOptions(filename, LoadOptions{})
+}
+
+//LoadWorldWithOptions loads the world file stored at filename
+//like LoadWorld, opts are used to load the maps
+func LoadWorldWithOptions(filename string, opts LoadOptions) (*World, error) {
+	return loadWorld(osFiles{}, filepath.ToSlash(filename), opts)
+}
+
+//LoadWorldFS loads the world file stored at name in fsys,
+//maps are loaded relative to the world file from fsys
+func LoadWorldFS(fsys fs.FS, name string) (*World, error) {
+	return LoadWorldFSWithOptions(fsys, name, LoadOptions{})
+}
+
+//LoadWorldFSWithOptions loads the world file stored at name
+//in fsys like LoadWorldFS, opts are used to load the maps
+func LoadWorldFSWithOptions(fsys fs.FS, name string, opts LoadOptions) (*World, error) {
+	return loadWorld(fsFiles{fsys: fsys}, name, opts)
+}
+
+//loadWorld decodes the world file and places all
+//explicitly listed maps and maps matching a pattern
+func loadWorld(files worldFiles, name string, opts LoadOptions) (*World, error) {
+	f, err := files.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded worldFile
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("could not decode %s: %w", name, err)
+	}
+
+	if decoded.Type != "" && decoded.Type != "world" {
+		return nil, fmt.Errorf("expected a world but got %s", decoded.Type)
+	}
+
+	w := &World{
+		OnlyShowAdjacentMaps: decoded.OnlyShowAdjacentMaps,
+		filename:             name,
+		files:                files,
+		opts:                 opts,
+	}
+
+	known := map[string]bool{}
+	for _, m := range decoded.Maps {
+		w.Maps = append(w.Maps, &WorldMap{
+			FileName: m.FileName,
+			X:        m.X,
+			Y:        m.Y,
+			Width:    m.Width,
+			Height:   m.Height,
+			world:    w,
+		})
+		known[w.resolve(m.FileName)] = true
+	}
+
+	if len(decoded.Patterns) == 0 {
+		return w, nil
+	}
+
+	entries, err := files.readDir(path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, p := range decoded.Patterns {
+		matched, err := p.match(entries, w)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range matched {
+			if !known[w.resolve(m.FileName)] {
+				known[w.resolve(m.FileName)] = true
+				w.Maps = append(w.Maps, m)
+			}
+		}
+	}
+
+	return w, nil
+}
+
+//match places all entries whose name matches p
+func (p worldPattern) match(entries []fs.DirEntry, w *World) ([]*WorldMap, error) {
+	expression, err := regexp.Compile("^(?:" + p.Regexp + ")$")
+	if err != nil {
+		return nil, err
+	}
+
+	if expression.NumSubexp() < 2 {
+		return nil, fmt.Errorf("pattern %s needs two submatches for the map position", p.Regexp)
+	}
+
+	multiplierX := intOrDefault(p.MultiplierX, 1)
+	multiplierY := intOrDefault(p.MultiplierY, 1)
+
+	var matched []*WorldMap
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		submatches := expression.FindStringSubmatch(entry.Name())
+		if submatches == nil {
+			continue
+		}
+
+		x, err := strconv.Atoi(submatches[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid x position of %s: %w", entry.Name(), err)
+		}
+
+		y, err := strconv.Atoi(submatches[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid y position of %s: %w", entry.Name(), err)
+		}
+
+		matched = append(matched, &WorldMap{
+			FileName: entry.Name(),
+			X:        x*multiplierX + p.OffsetX,
+			Y:        y*multiplierY + p.OffsetY,
+			Width:    intOrDefault(p.MapWidth, multiplierX),
+			Height:   intOrDefault(p.MapHeight, multiplierY),
+			world:    w,
+		})
+	}
+
+	return matched, nil
+}
+
+//intOrDefault returns the value of i if it is set
+func intOrDefault(i *int, def int) int {
+	if i == nil {
+		return def
+	}
+
+	return *i
+}
+
+//resolve returns the path of a map relative to the world file
+func (w *World) resolve(fileName string) string {
+	return resolverOrDefault(w.opts.PathResolver).Resolve(w.filename, fileName)
+}
+
+//Bounds returns the rectangle covered by the map in world pixels
+func (wm *WorldMap) Bounds() image.Rectangle {
+	return image.Rect(wm.X, wm.Y, wm.X+wm.Width, wm.Y+wm.Height)
+}
+
+//Map loads the map on first access, it is safe
+//for concurrent use. Failed loads are retried.
+func (wm *WorldMap) Map() (*Map, error) {
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	if wm.m != nil {
+		return wm.m, nil
+	}
+
+	m, err := wm.world.files.loadMap(wm.world.resolve(wm.FileName), wm.world.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	wm.m = m
+
+	return m, nil
+}
+
+//IsLoaded returns true if the map has already been loaded
+func (wm *WorldMap) IsLoaded() bool {
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	return wm.m != nil
+}
+
+//MapsIn returns all maps that intersect with r
+func (w *World) MapsIn(r image.Rectangle) []*WorldMap {
+	var result []*WorldMap
+	for _, m := range w.Maps {
+		if m.Bounds().Overlaps(r) {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
+
+//MapsAt returns all maps that contain p
+func (w *World) MapsAt(p image.Point) []*WorldMap {
+	var result []*WorldMap
+	for _, m := range w.Maps {
+		if p.In(m.Bounds()) {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
+
+//worldRenderer renders all maps of a world region
+type worldRenderer struct {
+	world     *World
+	canvas    ImageCanvas
+	region    image.Rectangle
+	opts      RenderOptions
+	renderers map[*WorldMap]ContextRenderer
+}
+
+//NewWorldRenderer renders region of w on c, the top left corner of
+//region is drawn at the top left corner of c. Every map is clipped to
+//its world bounds and only loaded if it intersects region.
+//Without a Locator images are loaded like the maps of the world.
+func NewWorldRenderer(w *World, c ImageCanvas, region image.Rectangle, opts RenderOptions) ContextRenderer {
+	if opts.Locator == nil {
+		opts.Locator = NewLazyResourceLocator(w.files.locator())
+	}
+
+	return &worldRenderer{
+		world:     w,
+		canvas:    c,
+		region:    region,
+		opts:      opts,
+		renderers: map[*WorldMap]ContextRenderer{},
+	}
+}
+
+//Render draws all maps in the region
+func (r *worldRenderer) Render(elapsedTime int64) error {
+	return r.RenderContext(context.Background(), elapsedTime)
+}
+
+//RenderContext renders like Render but stops
+//as soon as ctx is done and returns its error
+func (r *worldRenderer) RenderContext(ctx context.Context, elapsedTime int64) error {
+	for _, wm := range r.world.MapsIn(r.region) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		renderer, ok := r.renderers[wm]
+		if !ok {
+			m, err := wm.Map()
+			if err != nil {
+				return err
+			}
+
+			offset := wm.Bounds().Min.Sub(r.region.Min).Add(r.canvas.Bounds().Min)
+			shifted := offsetCanvas{ImageCanvas: r.canvas, offset: offset}
+			clip := image.Rect(0, 0, wm.Width, wm.Height).Intersect(shifted.Bounds())
+			renderer = NewRendererWithOptions(*m, clippedCanvas{ImageCanvas: shifted, clip: clip}, r.opts).(ContextRenderer)
+			r.renderers[wm] = renderer
+		}
+
+		if err := renderer.RenderContext(ctx, elapsedTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package tmx_test
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"io/fs"
+	"io/ioutil"
+	"testing/fstest"
+
+	. "github.com/manyminds/tmx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Test worlds", func() {
+	//expectedRegion composes the expected rendering of the
+	//region (384, 0)-(1152, 768) from the rendered maps
+	expectedRegion := func() image.Image {
+		expected := image.NewRGBA(image.Rect(0, 0, 768, 768))
+		left := loadTestImage("testfiles/simple_example_zlib_expected.png")
+		right := loadTestImage("testfiles/animated_example_zlib_01.png")
+		draw.Draw(expected, image.Rect(0, 0, 384, 768), left, image.Pt(384, 0), draw.Src)
+		draw.Draw(expected, image.Rect(384, 0, 768, 768), right, image.Pt(0, 0), draw.Src)
+		return expected
+	}
+
+	expectWorld := func(w *World) {
+		Expect(w.Maps).To(HaveLen(3))
+		Expect(w.Maps[0].FileName).To(Equal("../simple_example_zlib.tmx"))
+		Expect(w.Maps[0].Bounds()).To(Equal(image.Rect(0, 0, 768, 768)))
+		Expect(w.Maps[1].FileName).To(Equal("map_0_1.tmx"))
+		Expect(w.Maps[1].Bounds()).To(Equal(image.Rect(0, 768, 768, 1536)))
+		Expect(w.Maps[2].FileName).To(Equal("map_1_0.tmx"))
+		Expect(w.Maps[2].Bounds()).To(Equal(image.Rect(768, 0, 1536, 768)))
+
+		for _, m := range w.Maps {
+			Expect(m.IsLoaded()).To(BeFalse())
+		}
+	}
+
+	It("loads explicit maps and maps matching patterns", func() {
+		w, err := LoadWorld("testfiles/world/example.world")
+		Expect(err).ToNot(HaveOccurred())
+		expectWorld(w)
+	})
+
+	It("finds maps by point and rectangle", func() {
+		w, err := LoadWorld("testfiles/world/example.world")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(w.MapsAt(image.Pt(10, 10))).To(Equal([]*WorldMap{w.Maps[0]}))
+		Expect(w.MapsAt(image.Pt(768, 10))).To(Equal([]*WorldMap{w.Maps[2]}))
+		Expect(w.MapsAt(image.Pt(800, 800))).To(BeEmpty())
+		Expect(w.MapsAt(image.Pt(-1, 0))).To(BeEmpty())
+
+		Expect(w.MapsIn(image.Rect(700, 700, 800, 800))).To(Equal(w.Maps))
+		Expect(w.MapsIn(image.Rect(0, 0, 768, 768))).To(Equal([]*WorldMap{w.Maps[0]}))
+		Expect(w.MapsIn(image.Rect(1536, 0, 2000, 2000))).To(BeEmpty())
+	})
+
+	It("loads maps lazily", func() {
+		w, err := LoadWorld("testfiles/world/example.world")
+		Expect(err).ToNot(HaveOccurred())
+
+		m, err := w.MapsAt(image.Pt(800, 10))[0].Map()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Layers).To(HaveLen(4))
+		Expect(w.Maps[2].IsLoaded()).To(BeTrue())
+		Expect(w.Maps[0].IsLoaded()).To(BeFalse())
+		Expect(w.Maps[1].IsLoaded()).To(BeFalse())
+
+		again, err := w.Maps[2].Map()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again).To(BeIdenticalTo(m))
+	})
+
+	It("renders regions spanning multiple maps", func() {
+		w, err := LoadWorld("testfiles/world/example.world")
+		Expect(err).ToNot(HaveOccurred())
+
+		c := NewImageCanvas(image.Rect(0, 0, 768, 768))
+		renderer := NewWorldRenderer(w, c, image.Rect(384, 0, 1152, 768), RenderOptions{})
+		Expect(renderer.Render(0)).To(Succeed())
+		Expect(expectedRegion()).To(EqualImage(c.Image()))
+		Expect(w.Maps[1].IsLoaded()).To(BeFalse())
+	})
+
+	It("only fills the background of maps", func() {
+		w, err := LoadWorld("testfiles/world/example.world")
+		Expect(err).ToNot(HaveOccurred())
+
+		c := NewImageCanvas(image.Rect(0, 0, 768, 768))
+		renderer := NewWorldRenderer(w, c, image.Rect(384, 384, 1152, 1152), RenderOptions{})
+		Expect(renderer.Render(0)).To(Succeed())
+
+		background := loadTestImage("testfiles/simple_example_expected.png")
+		Expect(c.Image().At(0, 767)).To(Equal(background.At(384, 383)))
+		_, _, _, alpha := c.Image().At(767, 767).RGBA()
+		Expect(alpha).To(BeZero())
+	})
+
+	It("loads worlds from a fs.FS", func() {
+		fsys := fstest.MapFS{}
+		for _, name := range []string{
+			"world/example.world",
+			"world/map_0_1.tmx",
+			"world/map_1_0.tmx",
+			"simple_example_zlib.tmx",
+			"chipset.png",
+		} {
+			data, err := ioutil.ReadFile("testfiles/" + name)
+			Expect(err).ToNot(HaveOccurred())
+			fsys[name] = &fstest.MapFile{Data: data}
+		}
+
+		w, err := LoadWorldFS(fsys, "world/example.world")
+		Expect(err).ToNot(HaveOccurred())
+		expectWorld(w)
+
+		c := NewImageCanvas(image.Rect(0, 0, 768, 768))
+		renderer := NewWorldRenderer(w, c, image.Rect(384, 0, 1152, 768), RenderOptions{})
+		Expect(renderer.Render(0)).To(Succeed())
+		Expect(expectedRegion()).To(EqualImage(c.Image()))
+	})
+
+	It("fails for invalid worlds", func() {
+		fsys := fstest.MapFS{
+			"invalid.world": &fstest.MapFile{Data: []byte(`{"patterns": [{"regexp": "map_(\\d+)\\.tmx"}]}`)},
+			"missing.world": &fstest.MapFile{Data: []byte(`{"maps": [{"fileName": "missing.tmx", "width": 10, "height": 10}]}`)},
+			"map_1.tmx":     &fstest.MapFile{Data: []byte(`<map width="1" height="1"></map>`)},
+		}
+
+		_, err := LoadWorldFS(fsys, "invalid.world")
+		Expect(err).To(HaveOccurred())
+
+		w, err := LoadWorldFS(fsys, "missing.world")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = w.Maps[0].Map()
+		Expect(errors.Is(err, fs.ErrNotExist)).To(BeTrue())
+
+		c := NewImageCanvas(image.Rect(0, 0, 10, 10))
+		Expect(NewWorldRenderer(w, c, c.Bounds(), RenderOptions{}).Render(0)).ToNot(Succeed())
+	})
+})